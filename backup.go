@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// backupFileName is a timestamped name next to the database file.
+func backupFileName(file string, t time.Time) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "-" + t.Format("20060102-150405") + ext
+}
+
+// backupDatabase writes a consistent copy of the open database using a read
+// transaction, so it can be taken while the database is in use.
+func backupDatabase(file string) error {
+	if _, err := os.Stat(file); err == nil {
+		return fmt.Errorf("%s already exists", file)
+	}
+	return useDB(false, func(d *bbolt.DB) error {
+		return d.View(func(tx *bbolt.Tx) error {
+			return tx.CopyFile(file, 0o666)
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// uiUpdates hands updates from a background goroutine to the ui. The goroutine never
+// waits for the ui, which may itself wait for the goroutine, e.g. to close the database.
+type uiUpdates struct {
+	mu      sync.Mutex
+	pending []func()
+	queued  bool
+}
+
+// queue adds an update, which runs after the ones queued before.
+func (u *uiUpdates) queue(update func()) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.pending = append(u.pending, update)
+	if !u.queued {
+		u.queued = true
+		go app.QueueUpdateDraw(u.run)
+	}
+}
+
+func (u *uiUpdates) run() {
+	u.mu.Lock()
+	pending := u.pending
+	u.pending, u.queued = nil, false
+	u.mu.Unlock()
+	for _, update := range pending {
+		update()
+	}
+}
+
+// checkDatabase runs the bbolt consistency check in a read transaction and calls report
+// for every problem found. It returns the number of pages of the file and of problems.
+func checkDatabase(checked *bbolt.DB, report func(error)) (int64, int, error) {
+	var pages int64
+	problems := 0
+	err := checked.View(func(tx *bbolt.Tx) error {
+		pages = tx.Size() / int64(checked.Info().PageSize)
+		for err := range tx.Check() {
+			problems++
+			report(err)
+		}
+		return nil
+	})
+	return pages, problems, err
+}
+
+func checkSummary(pages int64, problems int) string {
+	return fmt.Sprintf("file of %d pages checked, %d problems found", pages, problems)
+}
+
+// checkView runs the check in the background and streams the problems found into a
+// scrollable report.
+func checkView() *tview.TextView {
+	report := tview.NewTextView().SetScrollable(true)
+	report.SetBorder(true).SetTitle("Integrity check of " + old + " (running)")
+	file := old
+	checked, release, err := acquireDB(false)
+	if err != nil {
+		fmt.Fprintln(report, "check failed:", err)
+		report.SetTitle("Integrity check of " + file + " (esc to close)")
+		return report
+	}
+	// closing the database waits for the read transaction of the check
+	updates := &uiUpdates{}
+	go func() {
+		pages, problems, err := checkDatabase(checked, func(problem error) {
+			updates.queue(func() {
+				fmt.Fprintln(report, problem)
+			})
+		})
+		release()
+		updates.queue(func() {
+			if err != nil {
+				fmt.Fprintln(report, "check failed:", err)
+			}
+			fmt.Fprintf(report, "\n%s\n", checkSummary(pages, problems))
+			report.SetTitle("Integrity check of " + file + " (done, esc to close)")
+		})
+	}()
+	return report
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+type command struct {
+	usage string
+	help  string
+	run   func(args []string) error
+}
+
+var errUsage = errors.New("usage")
+
+// commands are run against the database file without starting the terminal ui.
+var commands = map[string]command{
+	"ls":       {"ls db [bucket...]", "list keys and buckets (buckets end with /)", lsCommand},
+	"get":      {"get db path...", "write value of key to stdout", getCommand},
+	"put":      {"put db bucket... key value", "add key; a value of - is read from stdin", putCommand},
+	"rm":       {"rm db path...", "delete key or bucket", rmCommand},
+	"mkbucket": {"mkbucket db bucket...", "create bucket and any missing parents", mkbucketCommand},
+	"mv":       {"mv db path... -- newpath...", "move key or bucket", mvCommand},
+	"cp":       {"cp db path... -- newpath...", "copy key or bucket", cpCommand},
+	"export":   {"export [-o file] db [path...]", "write key, bucket or whole database as json", exportCommand},
+	"import":   {"import db file [bucket...]", "add keys and buckets from json export (- for stdin)", importCommand},
+	"backup":   {"backup db [file]", "write a consistent copy of the database (default db-timestamp)", backupCommand},
+	"check":    {"check db", "check database consistency; exit code 1 if problems are found", checkCommand},
+	"compact": {
+		"compact [-fill percent] [-pagesize n] db newfile", "write a compacted copy of the database", compactCommand,
+	},
+	"diff": {
+		"diff [-json] [-a path] [-b path] db [db2]", "list changes from bucket a to bucket b (of db2)", diffCommand,
+	},
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: %s [flags] [dbfile...]\n", os.Args[0])
+	fmt.Fprintf(out, "       %s [flags] command args...\n\ncommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-42s %s\n", commands[name].usage, commands[name].help)
+	}
+	fmt.Fprintln(out, "\nflags:")
+	flag.PrintDefaults()
+}
+
+// runCommand executes a non-interactive command and returns the process exit code.
+func runCommand(name string, args []string) int {
+	cmd := commands[name]
+	// commands use the file for their whole run
+	shared = false
+	err := cmd.run(args)
+	CloseDatabase()
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, "usage:", os.Args[0], cmd.usage)
+		return 2
+	default:
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", os.Args[0], name, err)
+		return 1
+	}
+}
+
+// openArgs opens the database named by the first argument and returns the remaining arguments.
+func openArgs(args []string, minArgs int) ([]string, error) {
+	if len(args) < minArgs+1 {
+		return nil, errUsage
+	}
+	if err := InitDatabase(args[0], openReadOnly); err != nil {
+		if errors.Is(err, errNoDatabase) {
+			return nil, fmt.Errorf("%w (use -create to create it)", err)
+		}
+		return nil, err
+	}
+	return args[1:], nil
+}
+
+// splitPaths splits arguments of the form path... -- newpath...
+func splitPaths(args []string) ([]string, []string, error) {
+	i := slices.Index(args, "--")
+	if i < 1 || i == len(args)-1 {
+		return nil, nil, errUsage
+	}
+	return args[:i], args[i+1:], nil
+}
+
+func lsCommand(args []string) error {
+	path, err := openArgs(args, 0)
+	if err != nil {
+		return err
+	}
+	return db.View(func(tx *bbolt.Tx) error {
+		if len(path) == 0 {
+			return tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+				_, err := fmt.Printf("%s/\n", name)
+				return err
+			})
+		}
+		bucket, err := getBucket(path, tx)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if v == nil {
+				_, err := fmt.Printf("%s/\n", k)
+				return err
+			}
+			_, err := fmt.Printf("%s\n", k)
+			return err
+		})
+	})
+}
+
+func getCommand(args []string) error {
+	path, err := openArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	node, err := lookupNode(path)
+	if err != nil {
+		return err
+	}
+	if node.kind == "bucket" {
+		return errors.New("path is a bucket")
+	}
+	_, err = os.Stdout.Write(node.value)
+	return err
+}
+
+func putCommand(args []string) error {
+	path, err := openArgs(args, 3)
+	if err != nil {
+		return err
+	}
+	value := path[len(path)-1]
+	if value == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		value = string(data)
+	}
+	return addKey(path[:len(path)-2], path[len(path)-2], value)
+}
+
+func rmCommand(args []string) error {
+	path, err := openArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	node, err := lookupNode(path)
+	if err != nil {
+		return err
+	}
+	return deleteEntry(node)
+}
+
+func mkbucketCommand(args []string) error {
+	path, err := openArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	return addBucket(path[:len(path)-1], path[len(path)-1])
+}
+
+func mvCommand(args []string) error {
+	rest, err := openArgs(args, 3)
+	if err != nil {
+		return err
+	}
+	path, newpath, err := splitPaths(rest)
+	if err != nil {
+		return err
+	}
+	node, err := lookupNode(path)
+	if err != nil {
+		return err
+	}
+	return moveItem(node, newpath)
+}
+
+func cpCommand(args []string) error {
+	rest, err := openArgs(args, 3)
+	if err != nil {
+		return err
+	}
+	path, newpath, err := splitPaths(rest)
+	if err != nil {
+		return err
+	}
+	node, err := lookupNode(path)
+	if err != nil {
+		return err
+	}
+	return copyItem(node, newpath)
+}
+
+func exportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "write to `file` instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	path, err := openArgs(flags.Args(), 0)
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		return exportFile(*output, path)
+	}
+	return writeExport(os.Stdout, path)
+}
+
+func importCommand(args []string) error {
+	rest, err := openArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	entries, err := readExportFile(rest[0])
+	if err != nil {
+		return err
+	}
+	return importEntries(rest[1:], entries)
+}
+
+// diffCommand prints one line per difference: change, type and path, separated by tabs.
+// With -json each line is a json object instead. Paths use the dialog path syntax.
+func diffCommand(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "write json lines")
+	pathA := flags.String("a", "", "`path` of the old bucket")
+	pathB := flags.String("b", "", "`path` of the new bucket")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	rest, err := openArgs(flags.Args(), 0)
+	if err != nil {
+		return err
+	}
+	oldPath, err := parsePath(*pathA)
+	if err != nil {
+		return err
+	}
+	newPath, err := parsePath(*pathB)
+	if err != nil {
+		return err
+	}
+	newDB := db
+	if len(rest) > 0 {
+		other, closeOther, err := openOther(rest[0])
+		if err != nil {
+			return err
+		}
+		defer closeOther()
+		newDB = other
+	}
+	diffs, err := diffDatabases(db, oldPath, newDB, newPath)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	for _, diff := range diffs {
+		kind := "key"
+		if diff.bucket {
+			kind = "bucket"
+		}
+		if *asJSON {
+			err = encoder.Encode(map[string]string{"change": diff.change, "type": kind, "path": formatPath(diff.path)})
+		} else {
+			_, err = fmt.Printf("%s\t%s\t%s\n", diff.change, kind, formatPath(diff.path))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCommand prints one line per problem found by the consistency check and a summary to stderr.
+func checkCommand(args []string) error {
+	rest, err := openArgs(args, 0)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errUsage
+	}
+	pages, problems, err := checkDatabase(db, func(problem error) {
+		fmt.Println(problem)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, checkSummary(pages, problems))
+	if problems > 0 {
+		return fmt.Errorf("%d problems found", problems)
+	}
+	return nil
+}
+
+// compactCommand writes a compacted copy and prints the sizes before and after.
+func compactCommand(args []string) error {
+	flags := flag.NewFlagSet("compact", flag.ContinueOnError)
+	fillText := flags.String("fill", "100", "fill `percent` of the pages")
+	pageSizeText := flags.String("pagesize", "", "page `size` of the new file (default page size of db)")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	rest, err := openArgs(flags.Args(), 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return errUsage
+	}
+	if *pageSizeText == "" {
+		*pageSizeText = strconv.Itoa(dbPageSize())
+	}
+	fill, pageSize, err := compactOptions(*fillText, *pageSizeText)
+	if err != nil {
+		return err
+	}
+	if _, err := compactDatabase(db, rest[0], fill, pageSize, nil); err != nil {
+		return err
+	}
+	_, err = fmt.Printf("%s: %d bytes\n%s: %d bytes\n", old, fileSize(old), rest[0], fileSize(rest[0]))
+	return err
+}
+
+// backupCommand copies the database to the named file or a timestamped file next to it.
+func backupCommand(args []string) error {
+	rest, err := openArgs(args, 0)
+	if err != nil {
+		return err
+	}
+	file := backupFileName(old, time.Now())
+	switch len(rest) {
+	case 0:
+	case 1:
+		file = rest[0]
+	default:
+		return errUsage
+	}
+	if err := backupDatabase(file); err != nil {
+		return err
+	}
+	_, err = fmt.Println(file)
+	return err
+}
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// lineCommand is a command of the command line. args are the kinds of its arguments,
+// path, file or text, which decide how they are parsed and completed; the first minArgs
+// are required.
+type lineCommand struct {
+	name     string
+	usage    string
+	help     string
+	args     []string
+	minArgs  int
+	mutating bool
+}
+
+// lineCommands run the operations of the main window on the open database. Paths are
+// bucket and key names separated by /, quoted and escaped like in the dialogs.
+var lineCommands = []lineCommand{
+	{"cp", "cp path newpath", "copy key or bucket", []string{"path", "path"}, 2, true},
+	{"mv", "mv path newpath", "move key or bucket", []string{"path", "path"}, 2, true},
+	{"rm", "rm path", "delete key or bucket", []string{"path"}, 1, true},
+	{"mkbucket", "mkbucket path", "create bucket", []string{"path"}, 1, true},
+	{"put", "put path value", "add key", []string{"path", "text"}, 2, true},
+	{"export", "export path [file]", "export key, bucket or database (/) to json", []string{"path", "file"}, 1, false},
+	{"import", "import file [bucket]", "import keys and buckets from json", []string{"file", "path"}, 1, true},
+	{"goto", "goto path", "select key or bucket", []string{"path"}, 1, false},
+	{"find", "find text", "find names or values containing text", []string{"text"}, 1, false},
+	{"open", "open file", "open database file", []string{"file"}, 1, false},
+	{"tab", "tab file", "open database file in a new tab", []string{"file"}, 1, false},
+	{"backup", "backup [file]", "write a backup of the database", []string{"file"}, 0, false},
+	{"undo", "undo", "undo last change", nil, 0, true},
+	{"redo", "redo", "redo last undone change", nil, 0, true},
+	{"quit", "quit", "close application", nil, 0, false},
+}
+
+var (
+	// commandLine replaces the footer of the main window while a command is entered
+	commandLine    *tview.InputField
+	commandHistory []string
+)
+
+// findLineCommand returns the command called name or the only one starting with it.
+func findLineCommand(name string) (lineCommand, error) {
+	found := []lineCommand{}
+	for _, cmd := range lineCommands {
+		if cmd.name == name {
+			return cmd, nil
+		}
+		if strings.HasPrefix(cmd.name, name) {
+			found = append(found, cmd)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return lineCommand{}, errors.New("unknown command " + name)
+	case 1:
+		return found[0], nil
+	}
+	names := []string{}
+	for _, cmd := range found {
+		names = append(names, cmd.name)
+	}
+	return lineCommand{}, fmt.Errorf("%s is ambiguous: %s", name, strings.Join(names, ", "))
+}
+
+// commandArgs splits text at spaces outside of quotes. The arguments keep their quotes
+// and escapes so paths can be split at / afterwards. last is the offset of the argument
+// being typed, which is the end of text if it ends with a space.
+func commandArgs(text string) (args []string, last int) {
+	start, quoted := -1, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == ' ' && !quoted {
+			if start >= 0 {
+				args = append(args, text[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		switch c {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			i++
+		}
+	}
+	if start < 0 {
+		return args, len(text)
+	}
+	return append(args, text[start:]), start
+}
+
+// parseArg parses an argument of kind, a path into its names and a file or text into
+// a single name.
+func parseArg(arg, kind string) ([]string, error) {
+	if kind == "path" {
+		return splitPath(arg, '/')
+	}
+	name, err := parseName(arg)
+	return []string{name}, err
+}
+
+// commandName formats a bucket or key name for the command line.
+func commandName(name string) string {
+	formatted := formatPathName(name)
+	if strings.Contains(formatted, "/") && !strings.HasPrefix(formatted, `"`) {
+		return strconv.Quote(name)
+	}
+	return formatted
+}
+
+// runLine runs a command line and reports its error. showRetry escapes the usages, which
+// have optional arguments in brackets.
+func runLine(line string) {
+	if err := runCommandLine(line); err != nil {
+		showRetry(err, func() { runLine(line) })
+	}
+}
+
+// runCommandLine parses and runs a command line.
+func runCommandLine(text string) error { //nolint:funlen,cyclop
+	args, _ := commandArgs(text)
+	if len(args) == 0 {
+		return nil
+	}
+	cmd, err := findLineCommand(args[0])
+	if err != nil {
+		return err
+	}
+	args = args[1:]
+	if len(args) < cmd.minArgs || len(args) > len(cmd.args) {
+		return errors.New("usage: " + cmd.usage)
+	}
+	if readOnly && cmd.mutating {
+		return errors.New("database is open read only")
+	}
+	parsed := make([][]string, len(args))
+	for i, arg := range args {
+		if parsed[i], err = parseArg(arg, cmd.args[i]); err != nil {
+			return err
+		}
+	}
+	switch cmd.name {
+	case "cp", "mv":
+		node, err := lookupNode(parsed[0])
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayPath(parsed[0]), err)
+		}
+		newpath := parsed[1]
+		if len(newpath) == 0 {
+			return errors.New("invalid destination path")
+		}
+		if cmd.name == "mv" {
+			err = record("move", [][]string{node.path, newpath}, func() error {
+				return moveItem(node, newpath)
+			})
+		} else {
+			err = record("copy", [][]string{newpath}, func() error {
+				return copyItem(node, newpath)
+			})
+		}
+		if err != nil {
+			return err
+		}
+		reloadAndSetSelection(newpath)
+	case "rm":
+		node, err := lookupNode(parsed[0])
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayPath(parsed[0]), err)
+		}
+		err = record("delete", [][]string{node.path}, func() error {
+			return deleteEntry(node)
+		})
+		if err != nil {
+			return err
+		}
+		reloadParent(node.path)
+	case "mkbucket", "put":
+		path := parsed[0]
+		if len(path) == 0 {
+			return errors.New("invalid path")
+		}
+		parent, name := path[:len(path)-1], path[len(path)-1]
+		if cmd.name == "put" {
+			err = record("add key", [][]string{path}, func() error {
+				return addKey(parent, name, parsed[1][0])
+			})
+		} else {
+			err = record("add bucket", [][]string{path}, func() error {
+				return addBucket(parent, name)
+			})
+		}
+		if err != nil {
+			return err
+		}
+		reloadAndSetSelection(path)
+	case "export":
+		file := exportFileName(parsed[0])
+		if len(parsed) > 1 {
+			file = parsed[1][0]
+		}
+		if err := exportFile(file, parsed[0]); err != nil {
+			return err
+		}
+		showInfo("exported to " + file)
+	case "import":
+		path := []string{}
+		if len(parsed) > 1 {
+			path = parsed[1]
+		}
+		entries, err := readExportFile(parsed[0][0])
+		if err != nil {
+			return err
+		}
+		paths := importPaths(path, entries)
+		err = record("import", paths, func() error {
+			return importEntries(path, entries)
+		})
+		if err != nil {
+			return err
+		}
+		if len(paths) > 0 {
+			path = paths[0]
+		}
+		reloadAndSetSelection(path)
+	case "goto":
+		if err := searchEntry(parsed[0]); err != nil {
+			return err
+		}
+		selectNode(parsed[0])
+	case "find":
+		hits, err := findEntries(parsed[0][0], false, true, true)
+		if err != nil {
+			return err
+		}
+		if len(hits) == 0 {
+			return errors.New("not found")
+		}
+		searchPattern, searchHits = parsed[0][0], hits
+		gotoHit(0)
+	case "open", "tab":
+		openFile(parsed[0][0], openReadOnly, cmd.name == "tab")
+	case "backup":
+		file := backupFileName(old, time.Now())
+		if len(parsed) > 0 {
+			file = parsed[0][0]
+		}
+		if err := backupDatabase(file); err != nil {
+			return err
+		}
+		showInfo(fmt.Sprintf("backup written to %s (%d bytes)", file, fileSize(file)))
+	case "undo":
+		revert(undo)
+	case "redo":
+		revert(redo)
+	case "quit":
+		runAction("quit")
+	}
+	return nil
+}
+
+// completions returns the completions of the argument being typed at the end of text:
+// command names, keys and buckets of the open database or file names. prefix is the
+// text before that argument.
+func completions(text string) (prefix string, found []string) {
+	args, last := commandArgs(text)
+	prefix, current := text[:last], text[last:]
+	index := len(args)
+	if last < len(text) {
+		index--
+	}
+	if index == 0 {
+		for _, cmd := range lineCommands {
+			if strings.HasPrefix(cmd.name, current) {
+				found = append(found, cmd.name+" ")
+			}
+		}
+		return prefix, found
+	}
+	cmd, err := findLineCommand(args[0])
+	if err != nil || index > len(cmd.args) {
+		return prefix, nil
+	}
+	switch cmd.args[index-1] {
+	case "path":
+		return prefix, completePath(current)
+	case "file":
+		return prefix, completeFile(current)
+	}
+	return prefix, nil
+}
+
+// completePath lists the keys and buckets in the bucket of a partial path. Buckets end
+// with / and keys with a space.
+func completePath(arg string) []string {
+	// the names before the last / outside of quotes are complete
+	split, quoted := 0, false
+	for i := 0; i < len(arg); i++ {
+		switch arg[i] {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			i++
+		case '/':
+			if !quoted {
+				split = i + 1
+			}
+		}
+	}
+	parent, err := splitPath(arg[:split], '/')
+	if err != nil {
+		return nil
+	}
+	start := ""
+	if split < len(arg) {
+		if start, err = parseName(arg[split:]); err != nil {
+			return nil
+		}
+	}
+	found := []string{}
+	add := func(name, value []byte) error {
+		if !strings.HasPrefix(string(name), start) {
+			return nil
+		}
+		end := " "
+		if value == nil {
+			end = "/"
+		}
+		found = append(found, arg[:split]+commandName(string(name))+end)
+		if len(found) == pageSize {
+			return errors.New("too many entries")
+		}
+		return nil
+	}
+	view(func(tx *bbolt.Tx) error { //nolint:errcheck
+		if len(parent) == 0 {
+			return tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+				return add(name, nil)
+			})
+		}
+		bucket, err := getBucket(parent, tx)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(add)
+	})
+	return found
+}
+
+// completeFile lists the files starting with a partial file name. Directories end with /.
+func completeFile(arg string) []string {
+	name := ""
+	if arg != "" {
+		var err error
+		if name, err = parseName(arg); err != nil {
+			return nil
+		}
+	}
+	dir, start := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	found := []string{}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), start) || (start == "" && strings.HasPrefix(file.Name(), ".")) {
+			continue
+		}
+		name := filepath.Join(dir, file.Name())
+		if file.IsDir() {
+			found = append(found, formatPathName(name)+"/")
+		} else {
+			found = append(found, formatPathName(name)+" ")
+		}
+	}
+	return found
+}
+
+// commonPrefix is the longest common prefix of the completions.
+func commonPrefix(found []string) string {
+	common := found[0]
+	for _, s := range found[1:] {
+		for !strings.HasPrefix(s, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	for !utf8.ValidString(common) {
+		common = common[:len(common)-1]
+	}
+	return common
+}
+
+// showCommandLine opens the command line in the footer of the main window. Tab completes
+// the argument being typed, up and down go through the previous commands.
+func showCommandLine(text string) { //nolint:funlen
+	input := tview.NewInputField().
+		SetLabel(":").
+		SetText(text).
+		SetAutocompleteUseTags(false)
+	history := len(commandHistory)
+	var prefix string
+	var found []string
+	// completions are only listed on tab, not while typing
+	listing := false
+	input.SetAutocompleteFunc(func(string) []string {
+		if !listing {
+			found = nil
+			return nil
+		}
+		shown := make([]string, 0, len(found))
+		for _, s := range found {
+			shown = append(shown, strings.TrimRight(s, " "))
+		}
+		return shown
+	})
+	input.SetAutocompletedFunc(func(_ string, index, source int) bool {
+		if source == tview.AutocompletedNavigate {
+			return false
+		}
+		input.SetText(prefix + found[index])
+		found = nil
+		return true
+	})
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyTab:
+			if found != nil {
+				// the list selects the highlighted completion
+				return event
+			}
+			var completed []string
+			prefix, completed = completions(input.GetText())
+			switch len(completed) {
+			case 0:
+			case 1:
+				input.SetText(prefix + completed[0])
+			default:
+				input.SetText(prefix + commonPrefix(completed))
+				found, listing = completed, true
+				input.Autocomplete()
+				listing = false
+			}
+			return nil
+		case tcell.KeyEsc:
+			found = nil
+		case tcell.KeyUp:
+			if found == nil && history > 0 {
+				history--
+				input.SetText(commandHistory[history])
+				return nil
+			}
+		case tcell.KeyDown:
+			if found == nil && history < len(commandHistory) {
+				history++
+				input.SetText("")
+				if history < len(commandHistory) {
+					input.SetText(commandHistory[history])
+				}
+				return nil
+			}
+		}
+		return event
+	})
+	input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			line := input.GetText()
+			closeCommandLine()
+			if strings.TrimSpace(line) == "" {
+				return
+			}
+			commandHistory = append(slices.DeleteFunc(commandHistory, func(s string) bool { return s == line }), line)
+			runLine(line)
+		case tcell.KeyEsc:
+			closeCommandLine()
+		}
+	})
+	commandLine = input
+	grid = mainGrid()
+	pager.AddPage("main", grid, true, true)
+	app.SetFocus(commandLine)
+}
+
+func closeCommandLine() {
+	commandLine = nil
+	grid = mainGrid()
+	pager.AddPage("main", grid, true, true)
+	app.SetFocus(tree)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// compactTxMaxSize limits the size of the write transactions of a compaction,
+// the same default as bbolt compact.
+const compactTxMaxSize = 65536
+
+var (
+	// compactions are the compactions running in the background
+	compactions sync.WaitGroup
+	// compactionsStopped makes the running compactions give up
+	compactionsStopped atomic.Bool
+
+	errCompactionStopped = errors.New("stopped because the database was closed or changed")
+)
+
+// compactor copies a database into a new file like bbolt.Compact, which always fills
+// pages completely and cannot report progress.
+type compactor struct {
+	dst      *bbolt.DB
+	tx       *bbolt.Tx
+	fill     float64
+	size     int64
+	entries  int
+	total    int
+	progress func(entries, total int)
+}
+
+// compactDatabase writes all buckets and keys of src into the new file. Pages are filled
+// to fill (0.1 - 1.0) and a page size of 0 uses the default. It returns the id of the
+// source transaction, so the caller can tell whether src changed in the meantime.
+func compactDatabase(src *bbolt.DB, file string, fill float64, pageSize int, progress func(entries, total int)) (int, error) {
+	if _, err := os.Stat(file); err == nil {
+		return 0, fmt.Errorf("%s already exists", file)
+	}
+	dst, err := bbolt.Open(file, 0o666, &bbolt.Options{Timeout: time.Second, PageSize: pageSize, NoSync: true})
+	if err != nil {
+		return 0, err
+	}
+	c := &compactor{dst: dst, fill: fill, progress: progress}
+	var id int
+	err = src.View(func(srcTx *bbolt.Tx) error {
+		id = srcTx.ID()
+		if err := srcTx.ForEach(func(_ []byte, b *bbolt.Bucket) error {
+			// KeyN also counts the entries of nested buckets, which next counts like keys
+			c.total += b.Stats().KeyN
+			return nil
+		}); err != nil {
+			return err
+		}
+		if c.tx, err = dst.Begin(true); err != nil {
+			return err
+		}
+		defer func() { c.tx.Rollback() }() //nolint:errcheck
+		if err := srcTx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+			return c.copyBucket([][]byte{name}, b)
+		}); err != nil {
+			return err
+		}
+		return c.tx.Commit()
+	})
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file)
+		return 0, err
+	}
+	if progress != nil {
+		progress(c.entries, c.total)
+	}
+	return id, nil
+}
+
+func (c *compactor) copyBucket(path [][]byte, src *bbolt.Bucket) error {
+	var created *bbolt.Bucket
+	var err error
+	if len(path) == 1 {
+		created, err = c.tx.CreateBucket(path[0])
+	} else {
+		if err := c.next(path[len(path)-1], nil); err != nil {
+			return err
+		}
+		created, err = c.bucket(path[:len(path)-1]).CreateBucket(path[len(path)-1])
+	}
+	if err != nil {
+		return err
+	}
+	if err := created.SetSequence(src.Sequence()); err != nil {
+		return err
+	}
+	return src.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return c.copyBucket(append(slices.Clone(path), k), src.Bucket(k))
+		}
+		if err := c.next(k, v); err != nil {
+			return err
+		}
+		return c.bucket(path).Put(k, v)
+	})
+}
+
+// next counts an entry and commits the transaction when it grows too large.
+// Buckets are looked up again after every commit.
+func (c *compactor) next(k, v []byte) error {
+	if compactionsStopped.Load() {
+		return errCompactionStopped
+	}
+	size := int64(len(k) + len(v))
+	if c.size > 0 && c.size+size > compactTxMaxSize {
+		if err := c.tx.Commit(); err != nil {
+			return err
+		}
+		var err error
+		if c.tx, err = c.dst.Begin(true); err != nil {
+			return err
+		}
+		c.size = 0
+	}
+	c.size += size
+	c.entries++
+	if c.progress != nil && c.entries%1000 == 0 {
+		c.progress(c.entries, c.total)
+	}
+	return nil
+}
+
+func (c *compactor) bucket(path [][]byte) *bbolt.Bucket {
+	b := c.tx.Bucket(path[0])
+	for _, name := range path[1:] {
+		b = b.Bucket(name)
+	}
+	b.FillPercent = c.fill
+	return b
+}
+
+// compactOptions validates the fill percent and page size of a compaction.
+func compactOptions(fillText, pageSizeText string) (float64, int, error) {
+	fill, err := strconv.Atoi(fillText)
+	if err != nil || fill < 10 || fill > 100 {
+		return 0, 0, errors.New("fill percent must be between 10 and 100")
+	}
+	pageSize, err := parsePageSize(pageSizeText)
+	if err != nil {
+		return 0, 0, err
+	}
+	return float64(fill) / 100, pageSize, nil
+}
+
+func parsePageSize(text string) (int, error) {
+	pageSize, err := strconv.Atoi(text)
+	if err != nil || pageSize < 1024 || pageSize&(pageSize-1) != 0 {
+		return 0, errors.New("page size must be a power of two of at least 1024")
+	}
+	return pageSize, nil
+}
+
+func compactFileName(file string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "-compact" + ext
+}
+
+func fileSize(file string) int64 {
+	info, err := os.Stat(file)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// replaceDatabase moves the compacted file over the open database and reopens it.
+// It refuses if the database was changed after the compaction started.
+func replaceDatabase(file, name string, id int) error {
+	if old != name {
+		return errors.New("another database was opened, not replaced")
+	}
+	if readOnly {
+		return errors.New("database is open read only")
+	}
+	current := 0
+	if err := useDB(false, func(d *bbolt.DB) error {
+		return d.View(func(tx *bbolt.Tx) error {
+			current = tx.ID()
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+	if current != id {
+		return errors.New("database changed during compaction, not replaced")
+	}
+	CloseDatabase()
+	if err := os.Rename(file, name); err != nil {
+		InitDatabase(name, readOnly) //nolint:errcheck
+		return err
+	}
+	return InitDatabase(name, readOnly)
+}
+
+// compactView compacts the open database in the background and shows its progress.
+func compactView(file string, fill float64, pageSize int, replace bool) *tview.TextView {
+	report := tview.NewTextView()
+	report.SetBorder(true).SetTitle("Compact " + old + " (running)")
+	name := old
+	before := fileSize(name)
+	src, release, err := acquireDB(false)
+	if err != nil {
+		report.SetText("compaction failed: " + err.Error()).SetTitle("Compact " + name + " (esc to close)")
+		return report
+	}
+	updates := &uiUpdates{}
+	compactions.Add(1)
+	go func() {
+		defer compactions.Done()
+		id, err := compactDatabase(src, file, fill, pageSize, func(entries, total int) {
+			updates.queue(func() {
+				report.SetText(fmt.Sprintf("copied %d of %d entries", entries, total))
+			})
+		})
+		release()
+		updates.queue(func() {
+			defer report.SetTitle("Compact " + name + " (done, esc to close)")
+			if err != nil {
+				fmt.Fprintln(report, "\n\ncompaction failed:", err)
+				return
+			}
+			after := fileSize(file)
+			fmt.Fprintf(report, "\n\n%s: %d bytes\n%s: %d bytes\n", name, before, file, after)
+			if before > 0 {
+				fmt.Fprintf(report, "%.1f%% of the original size\n", float64(after)*100/float64(before))
+			}
+			if !replace {
+				return
+			}
+			if err := replaceDatabase(file, name, id); err != nil {
+				fmt.Fprintf(report, "\n%v\n", err)
+				return
+			}
+			reloadTree()
+			fmt.Fprintf(report, "\nreplaced %s with the compacted file\n", name)
+		})
+	}()
+	return report
+}
+
+// stopCompactions stops the running compactions. Their read transactions would make
+// closing the database and writes that grow the file wait for the whole compaction.
+func stopCompactions() {
+	compactionsStopped.Store(true)
+	compactions.Wait()
+	compactionsStopped.Store(false)
+}
@@ -2,23 +2,31 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
+	"fmt"
+	"io/fs"
 	"log"
-	"strings"
-	"time"
+	"os"
+	"slices"
+	"syscall"
 
-	"github.com/gdamore/tcell/v2"
-	"github.com/rivo/tview"
 	"go.etcd.io/bbolt"
 )
 
 var (
-	db      *bbolt.DB
-	old     string
-	dbNodes = make(map[string]dbNode)
+	db       *bbolt.DB
+	old      string
+	readOnly bool
+
+	// dbOptions are used to open database files, set by flags and the config file
+	dbOptions bbolt.Options
+	// createFiles allows InitDatabase to create missing database files
+	createFiles bool
 )
 
+var errNoDatabase = errors.New("database file does not exist")
+
 type dbNode struct {
 	path  []string
 	kind  string
@@ -26,74 +34,159 @@ type dbNode struct {
 	value []byte
 }
 
-func InitDatabase(file string) error {
+// InitDatabase opens the database file. A file that cannot be opened for writing
+// is opened read-only instead. In shared mode the file is closed again right away.
+func InitDatabase(file string, ro bool) error {
 	var err error
+	// the open database stays open if the file is not created
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) && !createFiles {
+		return fmt.Errorf("%w: %s", errNoDatabase, file)
+	}
 	if db != nil {
 		CloseDatabase()
 	}
-	db, err = bbolt.Open(file, 0o666, &bbolt.Options{Timeout: time.Second})
+	db, err = bbolt.Open(file, 0o666, openOptions(ro))
+	if !ro && (errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS)) {
+		log.Println("file is not writable, opening read only", file, err)
+		ro = true
+		db, err = bbolt.Open(file, 0o666, openOptions(true))
+	}
+	if errors.Is(err, bbolt.ErrTimeout) {
+		err = lockError(file, err)
+	}
 	if err != nil {
+		db = nil
 		return err
 	}
+	if file != old {
+		discardTransaction()
+		clearJournal()
+		searchHits = nil
+	}
 	old = file
-	log.Println("loaded db file", file)
-	header.SetText("bbolt database file: " + file)
+	readOnly = ro
+	db.View(func(tx *bbolt.Tx) error { //nolint:errcheck
+		loadedTxID = tx.ID()
+		return nil
+	})
+	stale = false
+	if shared {
+		CloseDatabase()
+	}
+	log.Println("loaded db file", file, "read only", readOnly, "shared", shared)
+	setHeader()
 	return nil
 }
 
-func reloadDB() {
-	InitDatabase(old) //nolint:errcheck
+// openOptions are the options for opening a database file.
+func openOptions(ro bool) *bbolt.Options {
+	options := dbOptions
+	options.ReadOnly = ro
+	return &options
+}
+
+func setHeader() {
+	if header == nil {
+		return
+	}
+	text := "bbolt database file: " + old
+	if shared {
+		text += " (shared)"
+	}
+	text += "  [" + optionsSummary() + "]"
+	if readOnly {
+		text = "READ ONLY  " + text + "  READ ONLY"
+	}
+	if stale {
+		text += "  CHANGED ON DISK, press ctrl-R to reload"
+	}
+	if len(tabs) > 1 {
+		text += "\n" + tabLine()
+	}
+	header.SetText(text)
+}
+
+func reloadDB() error {
+	return InitDatabase(old, readOnly)
 }
 
 func CloseDatabase() {
 	if db != nil {
+		stopCompactions()
 		db.Close()
+		db = nil
 	}
 }
 
-func getNodes() []*tview.TreeNode {
-	nodes := []*tview.TreeNode{}
-	db.View(func(tx *bbolt.Tx) error { //nolint:errcheck
-		tx.ForEach(func(name []byte, b *bbolt.Bucket) error { //nolint:errcheck
-			node := process(name, nil, b)
-			nodes = append(nodes, node)
-			return nil
-		})
+// dbPageSize is the page size of the open database file.
+func dbPageSize() int {
+	pageSize := 0
+	useDB(false, func(d *bbolt.DB) error { //nolint:errcheck
+		pageSize = d.Info().PageSize
 		return nil
 	})
-	return nodes
+	return pageSize
 }
 
-func process(name []byte, path []string, b *bbolt.Bucket) *tview.TreeNode {
-	path = append(path, string(name))
-	dbNodes[strings.Join(path, " -> ")] = dbNode{
-		path: path,
-		name: name,
-		kind: "bucket",
-	}
-	node := tview.NewTreeNode(string(name)).SetReference(path).
-		SetSelectable(true).Collapse().SetColor(tcell.ColorGreen)
-	b.ForEach(func(k, v []byte) error { //nolint:errcheck
-		if v == nil {
-			nested := b.Bucket(k)
-			child := process(k, path, nested)
-			child.Collapse()
-			node.AddChild(child)
+// getChildren returns up to limit entries of the bucket at path, starting after the given key.
+// An empty path lists the root buckets. more reports whether further entries remain.
+func getChildren(path []string, after []byte, limit int) (nodes []dbNode, more bool, err error) {
+	err = view(func(tx *bbolt.Tx) error {
+		var cursor *bbolt.Cursor
+		if len(path) == 0 {
+			cursor = tx.Cursor()
 		} else {
-			childPath := append(path, string(k)) //nolint:gocritic
-			node.AddChild(tview.NewTreeNode(string(k)).SetReference(childPath).
-				SetSelectable(true)).Collapse()
-			dbNodes[strings.Join(childPath, " -> ")] = dbNode{
-				path:  childPath,
-				kind:  "key",
-				name:  k,
-				value: v,
+			bucket, err := getBucket(path, tx)
+			if err != nil {
+				return err
+			}
+			cursor = bucket.Cursor()
+		}
+		k, v := cursor.First()
+		if after != nil {
+			k, v = cursor.Seek(after)
+			if k != nil && bytes.Equal(k, after) {
+				k, v = cursor.Next()
+			}
+		}
+		for ; k != nil; k, v = cursor.Next() {
+			if len(nodes) == limit {
+				more = true
+				return nil
+			}
+			node := dbNode{
+				path: append(slices.Clone(path), string(k)),
+				kind: "key",
+				name: slices.Clone(k),
+			}
+			if v == nil {
+				node.kind = "bucket"
 			}
+			nodes = append(nodes, node)
 		}
 		return nil
 	})
+	return nodes, more, err
+}
 
-	return node
+func getValue(path []string) ([]byte, error) {
+	var value []byte
+	err := view(func(tx *bbolt.Tx) error {
+		parent, err := getParentBucket(path, tx)
+		if err != nil {
+			return err
+		}
+		if parent == nil {
+			return errors.New("invalid path: root entries are buckets")
+		}
+		v := parent.Get([]byte(path[len(path)-1]))
+		if v == nil {
+			return errors.New("invalid path: key does not exist")
+		}
+		value = slices.Clone(v)
+		return nil
+	})
+	return value, err
 }
 
 func renameEntry(node dbNode, value string) error {
@@ -105,11 +198,8 @@ func renameEntry(node dbNode, value string) error {
 }
 
 func renameKey(node dbNode, value string) error {
-	if db == nil {
-		return errors.New("database not open")
-	}
 	name := node.path[len(node.path)-1]
-	err := db.Update(func(tx *bbolt.Tx) error {
+	err := update(func(tx *bbolt.Tx) error {
 		b, err := getParentBucket(node.path, tx)
 		if err != nil {
 			return err
@@ -137,7 +227,7 @@ func renameBucket(node dbNode, name string) error {
 	newBucket := &bbolt.Bucket{}
 	oldBucket := &bbolt.Bucket{}
 	oldName := node.path[len(node.path)-1]
-	err := db.Update(func(tx *bbolt.Tx) error {
+	err := update(func(tx *bbolt.Tx) error {
 		b, err := getParentBucket(node.path, tx)
 		if err != nil {
 			return err
@@ -158,13 +248,7 @@ func renameBucket(node dbNode, name string) error {
 				return errors.New("invalid path: bucket does not exist")
 			}
 		}
-		err = oldBucket.ForEach(func(k, v []byte) error {
-			if err := newBucket.Put(k, v); err != nil {
-				return err
-			}
-			return nil
-		})
-		if err != nil {
+		if err := copyBucketContent(oldBucket, newBucket); err != nil {
 			return err
 		}
 		if b == nil {
@@ -182,8 +266,11 @@ func renameBucket(node dbNode, name string) error {
 }
 
 func searchEntry(path []string) error {
+	if len(path) == 0 {
+		return errors.New("invalid path")
+	}
 	var found bool
-	db.View(func(tx *bbolt.Tx) error { //nolint:errcheck
+	view(func(tx *bbolt.Tx) error { //nolint:errcheck
 		_, err := getBucket(path, tx)
 		if err == nil {
 			found = true
@@ -209,6 +296,35 @@ func searchEntry(path []string) error {
 	return nil
 }
 
+func lookupNode(path []string) (dbNode, error) {
+	node := dbNode{path: path}
+	if len(path) == 0 {
+		return node, errors.New("invalid path")
+	}
+	node.name = []byte(path[len(path)-1])
+	err := view(func(tx *bbolt.Tx) error {
+		if _, err := getBucket(path, tx); err == nil {
+			node.kind = "bucket"
+			return nil
+		}
+		if len(path) == 1 {
+			return errors.New("not found")
+		}
+		parent, err := getParentBucket(path, tx)
+		if err != nil {
+			return err
+		}
+		value := parent.Get(node.name)
+		if value == nil {
+			return errors.New("not found")
+		}
+		node.kind = "key"
+		node.value = slices.Clone(value)
+		return nil
+	})
+	return node, err
+}
+
 func getParentBucket(path []string, tx *bbolt.Tx) (*bbolt.Bucket, error) {
 	if len(path) == 1 {
 		// parent is root
@@ -224,9 +340,9 @@ func getBucket(path []string, tx *bbolt.Tx) (*bbolt.Bucket, error) {
 	}
 	for _, p := range path[1:] {
 		bucket = bucket.Bucket([]byte(p))
-	}
-	if bucket == nil {
-		return &bbolt.Bucket{}, errors.New("invalid path: bucket does not exit")
+		if bucket == nil {
+			return &bbolt.Bucket{}, errors.New("invalid path: bucket does not exit")
+		}
 	}
 	return bucket, nil
 }
@@ -240,7 +356,10 @@ func deleteEntry(node dbNode) error {
 
 func deleteBucket(node dbNode) error {
 	name := node.path[len(node.path)-1]
-	return db.Update(func(tx *bbolt.Tx) error {
+	if err := takeSnapshot("delete", node.path); err != nil {
+		return err
+	}
+	return update(func(tx *bbolt.Tx) error {
 		parent, err := getParentBucket(node.path, tx)
 		if err != nil {
 			return err
@@ -254,7 +373,7 @@ func deleteBucket(node dbNode) error {
 
 func deleteKey(node dbNode) error {
 	name := node.path[len(node.path)-1]
-	return db.Update(func(tx *bbolt.Tx) error {
+	return update(func(tx *bbolt.Tx) error {
 		parent, err := getParentBucket(node.path, tx)
 		if err != nil {
 			return err
@@ -267,7 +386,10 @@ func deleteKey(node dbNode) error {
 }
 
 func emptyBucket(node dbNode) error {
-	return db.Update(func(tx *bbolt.Tx) error {
+	if err := takeSnapshot("empty", node.path); err != nil {
+		return err
+	}
+	return update(func(tx *bbolt.Tx) error {
 		bucket, err := getBucket(node.path, tx)
 		if err != nil {
 			return err
@@ -284,7 +406,7 @@ func emptyBucket(node dbNode) error {
 
 func addBucket(path []string, name string) error {
 	log.Println("adding bucket", name, path)
-	return db.Update(func(tx *bbolt.Tx) error {
+	return update(func(tx *bbolt.Tx) error {
 		if len(path) == 0 {
 			_, err := tx.CreateBucket([]byte(name))
 			return err
@@ -299,7 +421,7 @@ func addBucket(path []string, name string) error {
 }
 
 func addKey(path []string, name, value string) error {
-	return db.Update(func(tx *bbolt.Tx) error {
+	return update(func(tx *bbolt.Tx) error {
 		bucket, err := createBucket(path, tx)
 		if err != nil {
 			return err
@@ -319,7 +441,11 @@ func copyItem(node dbNode, newpath []string) error {
 }
 
 func copyBucket(node dbNode, newpath []string) error {
-	return db.Update(func(tx *bbolt.Tx) error {
+	// the copy would be copied again while it is created
+	if withinPath(newpath, node.path) {
+		return errors.New("cannot copy a bucket into itself")
+	}
+	return update(func(tx *bbolt.Tx) error {
 		oldBucket, err := getBucket(node.path, tx)
 		if err != nil {
 			return err
@@ -328,18 +454,7 @@ func copyBucket(node dbNode, newpath []string) error {
 		if err != nil {
 			return err
 		}
-		return oldBucket.ForEach(func(k, v []byte) error {
-			if v == nil {
-				if err := copyBucketContent(oldBucket, bucket); err != nil {
-					return err
-				}
-			} else {
-				if err := bucket.Put(k, v); err != nil {
-					return err
-				}
-			}
-			return nil
-		})
+		return copyBucketContent(oldBucket, bucket)
 	})
 }
 
@@ -367,7 +482,7 @@ func copyKey(node dbNode, newpath []string) error {
 	if len(newpath) == 1 {
 		return errors.New("cannot create key in root bucket")
 	}
-	return db.Update(func(tx *bbolt.Tx) error {
+	return update(func(tx *bbolt.Tx) error {
 		bucket, err := createParentBucket(newpath, tx)
 		if err != nil {
 			return err
@@ -387,7 +502,7 @@ func moveKey(node dbNode, path []string) error {
 	if len(path) < 2 {
 		return errors.New("invalid path, destination too short")
 	}
-	return db.Update(func(tx *bbolt.Tx) error {
+	return update(func(tx *bbolt.Tx) error {
 		parent, err := getParentBucket(node.path, tx)
 		if err != nil {
 			return err
@@ -405,6 +520,16 @@ func moveKey(node dbNode, path []string) error {
 }
 
 func moveBucket(node dbNode, path []string) error {
+	if len(path) == 0 {
+		return errors.New("invalid path")
+	}
+	// bbolt does not check this, the bucket would be lost
+	if withinPath(path, node.path) {
+		return errors.New("cannot move a bucket into itself")
+	}
+	if err := takeSnapshot("move", node.path); err != nil {
+		return err
+	}
 	newname := path[len(path)-1]
 	if newname != string(node.name) {
 		// need to rename node first
@@ -413,7 +538,7 @@ func moveBucket(node dbNode, path []string) error {
 		}
 		node.name = []byte(newname)
 	}
-	return db.Update(func(tx *bbolt.Tx) error {
+	return update(func(tx *bbolt.Tx) error {
 		parent, err := getParentBucket(node.path, tx)
 		if err != nil {
 			return err
@@ -430,7 +555,15 @@ func moveBucket(node dbNode, path []string) error {
 	})
 }
 
+// withinPath reports whether path is bucket or lies below it.
+func withinPath(path, bucket []string) bool {
+	return len(path) >= len(bucket) && slices.Equal(path[:len(bucket)], bucket)
+}
+
 func createParentBucket(path []string, tx *bbolt.Tx) (*bbolt.Bucket, error) {
+	if len(path) == 0 {
+		return nil, errors.New("invalid path")
+	}
 	if len(path) == 1 {
 		return nil, nil //nolint:nilnil
 	}
@@ -438,7 +571,7 @@ func createParentBucket(path []string, tx *bbolt.Tx) (*bbolt.Bucket, error) {
 }
 
 func createBucket(path []string, tx *bbolt.Tx) (*bbolt.Bucket, error) {
-	if path == nil {
+	if len(path) == 0 {
 		return nil, errors.New("invalid path")
 	}
 	// create root bucket
@@ -456,24 +589,12 @@ func createBucket(path []string, tx *bbolt.Tx) (*bbolt.Bucket, error) {
 	return bucket, nil
 }
 
-func editNode(node dbNode, update string) error {
-	return db.Update(func(tx *bbolt.Tx) error {
+func editNode(node dbNode, value []byte) error {
+	return update(func(tx *bbolt.Tx) error {
 		bucket, err := getParentBucket(node.path, tx)
 		if err != nil {
 			return err
 		}
-		return bucket.Put(node.name, stringToJSON(update))
+		return bucket.Put(node.name, value)
 	})
 }
-
-func stringToJSON(s string) []byte { //nolint:varnamelen
-	var temp any
-	if err := json.Unmarshal([]byte(s), &temp); err != nil {
-		return []byte(s)
-	}
-	bytes, err := json.Marshal(temp)
-	if err != nil {
-		return []byte(s)
-	}
-	return bytes
-}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// openTestDatabase opens a new database file in a temporary directory as the open database.
+func openTestDatabase(t *testing.T) {
+	t.Helper()
+	log.SetOutput(io.Discard)
+	createFiles = true
+	dbOptions.Timeout = time.Second
+	t.Cleanup(func() {
+		CloseDatabase()
+		createFiles = false
+		old = ""
+		clearJournal()
+	})
+	if err := InitDatabase(filepath.Join(t.TempDir(), "test.db"), false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// listPaths returns the paths of all buckets and keys of the open database, buckets ending in /.
+func listPaths(t *testing.T) []string {
+	t.Helper()
+	entries, err := exportEntries(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{}
+	var walk func(prefix string, entries []*entry)
+	walk = func(prefix string, entries []*entry) {
+		for _, e := range entries {
+			name := prefix + string(e.name)
+			if !e.bucket {
+				paths = append(paths, name+"="+string(e.value))
+				continue
+			}
+			paths = append(paths, name+"/")
+			walk(name+"/", e.children)
+		}
+	}
+	walk("", entries)
+	return paths
+}
+
+func TestCopyItem(t *testing.T) {
+	openTestDatabase(t)
+	if err := addKey([]string{"a", "b"}, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		node    dbNode
+		newpath []string
+		wantErr bool
+	}{
+		{"key", dbNode{path: []string{"a", "b", "k"}, kind: "key", name: []byte("k"), value: []byte("v")}, []string{"c", "k"}, false},
+		{"bucket", dbNode{path: []string{"a", "b"}, kind: "bucket", name: []byte("b")}, []string{"d"}, false},
+		{"bucket into itself", dbNode{path: []string{"a"}, kind: "bucket", name: []byte("a")}, []string{"a", "sub"}, true},
+		{"bucket into nested bucket", dbNode{path: []string{"a"}, kind: "bucket", name: []byte("a")}, []string{"a", "b", "a"}, true},
+		{"key into root", dbNode{path: []string{"a", "b", "k"}, kind: "key", name: []byte("k"), value: []byte("v")}, []string{"k"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() { done <- copyItem(test.node, test.newpath) }()
+			select {
+			case err := <-done:
+				if (err != nil) != test.wantErr {
+					t.Errorf("copyItem() error = %v, want error %v", err, test.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("copyItem() does not return")
+			}
+		})
+	}
+	want := []string{"a/", "a/b/", "a/b/k=v", "c/", "c/k=v", "d/", "d/k=v"}
+	if got := listPaths(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("database = %v, want %v", got, want)
+	}
+}
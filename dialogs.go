@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"log"
-	"strings"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -22,7 +27,7 @@ func dialog(p tview.Primitive, w, h int) tview.Primitive { //nolint:ireturn,varn
 
 func addKeyForm(node dbNode, dialog string) *tview.Form {
 	form := tview.NewForm().
-		AddInputField("path:", strings.Join(node.path, " "), 0, nil, nil).
+		AddInputField("path:", formatPath(node.path), 0, nil, nil).
 		AddInputField("name", "", 0, nil, nil).
 		AddTextArea("value", "", 0, 12, 0, nil).
 		AddButton("Cancel", func() {
@@ -37,19 +42,32 @@ func addKeyForm(node dbNode, dialog string) *tview.Form {
 			form.SetBorderColor(tcell.ColorRed)
 		}
 	})
-	form.AddButton("Add", func() {
-		newpath := strings.Split(form.GetFormItem(0).(*tview.InputField).GetText(), " ")
-		name := form.GetFormItem(1).(*tview.InputField).GetText()
-		value := form.GetFormItem(2).(*tview.TextArea).GetText()
-		if err := addKey(newpath, name, value); err != nil {
+	var add func()
+	add = func() {
+		newpath, err := parsePath(form.GetFormItem(0).(*tview.InputField).GetText())
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		name, err := parseName(form.GetFormItem(1).(*tview.InputField).GetText())
+		if err != nil {
 			showError(err.Error())
 			return
 		}
+		value := form.GetFormItem(2).(*tview.TextArea).GetText()
+		err = record("add key", [][]string{append(newpath, name)}, func() error {
+			return addKey(newpath, name, value)
+		})
+		if err != nil {
+			showRetry(err, add)
+			return
+		}
 		reloadAndSetSelection(append(newpath, name))
 		tree.GetCurrentNode().Expand()
 		pager.RemovePage(dialog)
 		app.SetFocus(tree)
-	})
+	}
+	form.AddButton("Add", add)
 	form.SetButtonsAlign(tview.AlignCenter).
 		SetBorder(true).SetTitle("Add Key").SetTitleAlign(tview.AlignCenter)
 	return form
@@ -57,41 +75,50 @@ func addKeyForm(node dbNode, dialog string) *tview.Form {
 
 func addBucketForm(node dbNode, dialog string) *tview.Form {
 	form := tview.NewForm().
-		AddInputField("parent bucket:", strings.Join(node.path, " "), 0, nil, nil).
+		AddInputField("parent bucket:", formatPath(node.path), 0, nil, nil).
 		AddInputField("bucket name:", "", 0, nil, nil).
 		AddButton("Cancel", func() {
 			pager.RemovePage(dialog)
 			app.SetFocus(tree)
 		})
-	form.AddButton("Add", func() {
-		path := []string{}
-		parent := form.GetFormItem(0).(*tview.InputField).GetText()
-		if parent != "" {
-			path = strings.Split(parent, " ")
-		}
-		name := form.GetFormItem(1).(*tview.InputField).GetText()
-		if err := addBucket(path, name); err != nil {
+	var add func()
+	add = func() {
+		path, err := parsePath(form.GetFormItem(0).(*tview.InputField).GetText())
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		name, err := parseName(form.GetFormItem(1).(*tview.InputField).GetText())
+		if err != nil {
 			showError(err.Error())
 			return
 		}
+		err = record("add bucket", [][]string{append(path, name)}, func() error {
+			return addBucket(path, name)
+		})
+		if err != nil {
+			showRetry(err, add)
+			return
+		}
 		reloadAndSetSelection(append(path, name))
 		tree.GetCurrentNode().Expand()
 		pager.RemovePage(dialog)
 		app.SetFocus(tree)
-	}).AddTextView("to create root bucket", "use empty parent bucket", 0, 2, true, false)
+	}
+	form.AddButton("Add", add).AddTextView("to create root bucket", "use empty parent bucket", 0, 2, true, false)
 	form.SetButtonsAlign(tview.AlignCenter).
 		SetBorder(true).SetTitle("Add Bucket").SetTitleAlign(tview.AlignCenter)
 	return form
 }
 
 func deleteForm(node dbNode, dialog string) *tview.Form {
-	form := tview.NewForm()
-	form.AddTextView("path:", strings.Join(node.path, " "), 0, 1, false, false)
-	form.AddButton("Cancel", func() {
-		pager.RemovePage(dialog)
-	}).AddButton("Delete", func() {
-		if err := deleteEntry(node); err != nil {
-			showError(err.Error())
+	var remove func()
+	remove = func() {
+		err := record("delete", [][]string{node.path}, func() error {
+			return deleteEntry(node)
+		})
+		if err != nil {
+			showRetry(err, remove)
 			return
 		}
 		newpath := node.path[:len(node.path)-1]
@@ -99,35 +126,45 @@ func deleteForm(node dbNode, dialog string) *tview.Form {
 		selectNode(newpath)
 		tree.GetCurrentNode().Expand()
 		pager.RemovePage(dialog)
-	}).
+	}
+	form := tview.NewForm()
+	form.AddTextView("path:", formatPath(node.path), 0, 1, false, false)
+	form.AddButton("Cancel", func() {
+		pager.RemovePage(dialog)
+	}).AddButton("Delete", remove).
 		SetButtonsAlign(tview.AlignCenter)
 	form.SetBorder(true).SetTitle("Delete Item").SetTitleAlign(tview.AlignCenter)
 	return form
 }
 
 func emptyForm(node dbNode, dialog string) *tview.Form {
+	var empty func()
+	empty = func() {
+		err := record("empty bucket", [][]string{node.path}, func() error {
+			return emptyBucket(node)
+		})
+		if err != nil {
+			showRetry(err, empty)
+			return
+		}
+		reloadAndSetSelection(node.path)
+		pager.RemovePage(dialog)
+		app.SetFocus(tree)
+	}
 	form := tview.NewForm().
-		AddTextView("path:", strings.Join(node.path, " "), 0, 1, true, true).
+		AddTextView("path:", formatPath(node.path), 0, 1, true, true).
 		AddButton("Cancel", func() {
 			pager.RemovePage(dialog)
 			app.SetFocus(tree)
 		}).
-		AddButton("Empty", func() {
-			if err := emptyBucket(node); err != nil {
-				showError(err.Error())
-				return
-			}
-			reloadAndSetSelection(node.path)
-			pager.RemovePage(dialog)
-			app.SetFocus(tree)
-		}).
+		AddButton("Empty", empty).
 		SetButtonsAlign(tview.AlignCenter)
 	form.SetBorder(true).SetTitle("Empty Bucket").SetTitleAlign(tview.AlignCenter)
 	return form
 }
 
 func moveForm(node dbNode, dialog string) *tview.Form { //nolint:dupl
-	currentPath := strings.Join(node.path, " ")
+	currentPath := formatPath(node.path)
 	form := tview.NewForm().
 		AddTextView("current path", currentPath, 0, 1, true, true).
 		AddInputField("new path", currentPath, 0, nil, nil).
@@ -136,23 +173,48 @@ func moveForm(node dbNode, dialog string) *tview.Form { //nolint:dupl
 			app.SetFocus(tree)
 		}).
 		SetButtonsAlign(tview.AlignCenter)
-	form.AddButton("Submit", func() {
-		newpath := strings.Split(form.GetFormItem(1).(*tview.InputField).GetText(), " ")
-		log.Println("moving from", node.path, "to", newpath)
-		if err := moveItem(node, newpath); err != nil {
+	target := targetDropDown(form)
+	var submit func()
+	submit = func() {
+		newpath, err := parsePath(form.GetFormItem(1).(*tview.InputField).GetText())
+		if err != nil {
 			showError(err.Error())
 			return
 		}
+		if len(newpath) == 0 {
+			showError("invalid destination path")
+			return
+		}
+		if other := targetTab(form, target); other != activeTab {
+			log.Println("moving from", node.path, "to", newpath, "in", tabFile(other))
+			if err := moveToTab(node, other, newpath); err != nil {
+				showRetry(err, submit)
+				return
+			}
+			reloadTree()
+			pager.RemovePage(dialog)
+			selectTab(other)
+			return
+		}
+		log.Println("moving from", node.path, "to", newpath)
+		err = record("move", [][]string{node.path, newpath}, func() error {
+			return moveItem(node, newpath)
+		})
+		if err != nil {
+			showRetry(err, submit)
+			return
+		}
 		reloadAndSetSelection(newpath)
 		pager.RemovePage(dialog)
 		app.SetFocus(tree)
-	})
+	}
+	form.AddButton("Submit", submit)
 	form.SetBorder(true).SetTitle("Move Item").SetTitleAlign(tview.AlignCenter)
 	return form
 }
 
 func copyForm(node dbNode, dialog string) *tview.Form { //nolint:dupl
-	currentPath := strings.Join(node.path, " ")
+	currentPath := formatPath(node.path)
 	form := tview.NewForm().
 		AddTextView("source path", currentPath, 0, 1, true, true).
 		AddInputField("destination path", currentPath, 0, nil, nil).
@@ -161,43 +223,72 @@ func copyForm(node dbNode, dialog string) *tview.Form { //nolint:dupl
 			app.SetFocus(tree)
 		}).
 		SetButtonsAlign(tview.AlignCenter)
-	form.AddButton("Submit", func() {
-		newpath := strings.Split(form.GetFormItem(1).(*tview.InputField).GetText(), " ")
-		log.Println("moving from", node.path, "to", newpath)
-		if err := copyItem(node, newpath); err != nil {
+	target := targetDropDown(form)
+	var submit func()
+	submit = func() {
+		newpath, err := parsePath(form.GetFormItem(1).(*tview.InputField).GetText())
+		if err != nil {
 			showError(err.Error())
 			return
 		}
+		if len(newpath) == 0 {
+			showError("invalid destination path")
+			return
+		}
+		if other := targetTab(form, target); other != activeTab {
+			log.Println("copying from", node.path, "to", newpath, "in", tabFile(other))
+			if err := copyToTab(node, other, newpath); err != nil {
+				showRetry(err, submit)
+				return
+			}
+			pager.RemovePage(dialog)
+			selectTab(other)
+			return
+		}
+		log.Println("copying from", node.path, "to", newpath)
+		err = record("copy", [][]string{newpath}, func() error {
+			return copyItem(node, newpath)
+		})
+		if err != nil {
+			showRetry(err, submit)
+			return
+		}
 		reloadAndSetSelection(newpath)
 		pager.RemovePage(dialog)
 		app.SetFocus(tree)
-	})
+	}
+	form.AddButton("Submit", submit)
 	form.SetBorder(true).SetTitle("Copy Item").SetTitleAlign(tview.AlignCenter)
 	return form
 }
 
 func renameForm(node dbNode, dialog string) *tview.Form {
 	form := tview.NewForm()
-	form.AddTextView("path:", strings.Join(node.path, " "), 0, 1, true, false).
-		AddInputField("new name", node.path[len(node.path)-1], 0, nil, nil).
+	var rename func()
+	rename = func() {
+		newName, err := parseName(form.GetFormItem(1).(*tview.InputField).GetText())
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		newpath := append(slices.Clone(node.path[:len(node.path)-1]), newName)
+		err = record("rename", [][]string{node.path, newpath}, func() error {
+			return renameEntry(node, newName)
+		})
+		if err != nil {
+			showRetry(err, rename)
+			return
+		}
+		reloadTree()
+		selectNode(newpath)
+		pager.RemovePage("rename")
+	}
+	form.AddTextView("path:", formatPath(node.path), 0, 1, true, false).
+		AddInputField("new name", formatPathName(node.path[len(node.path)-1]), 0, nil, nil).
 		AddButton("cancel", func() {
 			pager.RemovePage(dialog)
 		}).
-		AddButton("Rename", func() {
-			newName := form.GetFormItem(1).(*tview.InputField).GetText()
-			if err := renameEntry(node, newName); err != nil {
-				showError(err.Error())
-				return
-			}
-			reloadDB()
-			root := tree.GetRoot()
-			root.SetChildren(getNodes())
-			tree.SetRoot(root)
-			newpath := node.path
-			newpath[len(node.path)-1] = newName
-			selectNode(newpath)
-			pager.RemovePage("rename")
-		}).
+		AddButton("Rename", rename).
 		SetButtonsAlign(tview.AlignCenter)
 	form.SetBorder(true).SetTitle("Rename").SetTitleAlign(tview.AlignCenter)
 	return form
@@ -205,28 +296,72 @@ func renameForm(node dbNode, dialog string) *tview.Form {
 
 func searchForm(dialog string) *tview.Form {
 	form := tview.NewForm()
+	var search func()
+	search = func() {
+		searchPath, err := parsePath(form.GetFormItem(0).(*tview.InputField).GetText())
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		if err := searchEntry(searchPath); err != nil {
+			showRetry(err, search)
+			return
+		}
+		selectNode(searchPath)
+		pager.RemovePage(dialog)
+	}
 	form.AddInputField("search path", "", 0, nil, nil).
 		AddButton("Cancel", func() {
 			pager.RemovePage(dialog)
 		}).
-		AddButton("Search", func() {
-			path := form.GetFormItem(0).(*tview.InputField).GetText()
-			searchPath := strings.Split(path, " ")
-			if err := searchEntry(searchPath); err != nil {
-				showError(err.Error())
-				return
-			}
-			selectNode(searchPath)
+		AddButton("Search", search)
+	form.SetBorder(true).SetTitle("Search").SetTitleAlign(tview.AlignCenter)
+	return form
+}
+
+func findForm(dialog string) *tview.Form {
+	form := tview.NewForm().
+		AddInputField("pattern:", searchPattern, 0, nil, nil).
+		AddCheckbox("regular expression:", false, nil).
+		AddCheckbox("match names:", true, nil).
+		AddCheckbox("match values:", true, nil).
+		AddButton("Cancel", func() {
 			pager.RemovePage(dialog)
+			app.SetFocus(tree)
 		})
-	form.SetBorder(true).SetTitle("Search").SetTitleAlign(tview.AlignCenter)
+	var find func()
+	find = func() {
+		pattern := form.GetFormItem(0).(*tview.InputField).GetText()
+		hits, err := findEntries(pattern,
+			form.GetFormItem(1).(*tview.Checkbox).IsChecked(),
+			form.GetFormItem(2).(*tview.Checkbox).IsChecked(),
+			form.GetFormItem(3).(*tview.Checkbox).IsChecked())
+		if err != nil {
+			showRetry(err, find)
+			return
+		}
+		if len(hits) == 0 {
+			showError("not found")
+			return
+		}
+		searchPattern = pattern
+		searchHits = hits
+		searchIndex = 0
+		pager.RemovePage(dialog)
+		results := modal(searchResults("results"), 80, 20)
+		pager.AddPage("results", results, true, true)
+		app.SetFocus(results)
+	}
+	form.AddButton("Find", find)
+	form.SetButtonsAlign(tview.AlignCenter).
+		SetBorder(true).SetTitle("Find").SetTitleAlign(tview.AlignCenter)
 	return form
 }
 
 func editForm(node dbNode, dialog string) *tview.Form {
-	value := prettyString(node.value)
+	mode := detectStorageMode(node.value)
 	form := tview.NewForm().
-		AddTextView("path:", strings.Join(node.path, " "), 0, 1, true, false).
+		AddTextView("path:", formatPath(node.path), 0, 1, true, false).
 		AddTextArea("value:", "", 0, 12, 0, nil).
 		AddButton("Cancel", func() {
 			pager.RemovePage(dialog)
@@ -241,17 +376,252 @@ func editForm(node dbNode, dialog string) *tview.Form {
 		}
 	})
 	form.AddButton("Submit", func() {
-		if err := editNode(node, form.GetFormItem(1).(*tview.TextArea).GetText()); err != nil {
-			showError(err.Error())
+		showStoreForm(node, form.GetFormItem(1).(*tview.TextArea).GetText(), mode, dialog)
+	})
+	form.SetButtonsAlign(tview.AlignCenter)
+	form.SetBorder(true).SetTitle("Edit Key").SetTitleAlign(tview.AlignCenter)
+	form.GetFormItem(1).(*tview.TextArea).SetText(editText(node.value, mode), false)
+	return form
+}
+
+// showStoreForm replaces the named page by the store form.
+func showStoreForm(node dbNode, text string, mode storageMode, name string) {
+	pager.AddPage(name, dialog(storeForm(node, text, mode, name), 86, 24), true, true)
+}
+
+// storeForm shows the bytes that the edited text is stored as in the chosen storage
+// mode and stores them.
+func storeForm(node dbNode, text string, mode storageMode, dialog string) *tview.Flex {
+	preview := tview.NewTextView().SetScrollable(true).SetWrap(false)
+	preview.SetBorder(true)
+	var value []byte
+	var encodeErr error
+	update := func(mode storageMode) {
+		value, encodeErr = encodeValue(text, mode)
+		if encodeErr != nil {
+			preview.SetText(encodeErr.Error()).SetTitle("Preview")
+			return
+		}
+		title := fmt.Sprintf("Preview: %d bytes, was %d bytes", len(value), len(node.value))
+		if bytes.Equal(value, node.value) {
+			title += ", unchanged"
+		}
+		preview.SetText(hex.Dump(value)).ScrollToBeginning().SetTitle(title)
+	}
+	update(mode)
+	form := tview.NewForm().
+		AddDropDown("store as:", storageModes, int(mode), func(_ string, index int) {
+			if index >= 0 {
+				update(storageMode(index))
+			}
+		}).
+		AddButton("Cancel", func() {
+			pager.RemovePage(dialog)
+			app.SetFocus(tree)
+		})
+	var store func()
+	store = func() {
+		if encodeErr != nil {
+			showError(encodeErr.Error())
+			return
+		}
+		err := record("edit", [][]string{node.path}, func() error {
+			return editNode(node, value)
+		})
+		if err != nil {
+			showRetry(err, store)
 			return
 		}
 		reloadAndSetSelection(node.path)
 		pager.RemovePage(dialog)
 		app.SetFocus(tree)
-	})
+	}
+	form.AddButton("Store", store)
 	form.SetButtonsAlign(tview.AlignCenter)
-	form.SetBorder(true).SetTitle("Edit Key").SetTitleAlign(tview.AlignCenter)
-	form.GetFormItem(1).(*tview.TextArea).SetText(value, false)
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(preview, 0, 1, false).
+		AddItem(form, 5, 0, true)
+	flex.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTAB && preview.HasFocus() {
+			app.SetFocus(form)
+			return nil
+		}
+		if event.Key() == tcell.KeyBacktab {
+			app.SetFocus(preview)
+			return nil
+		}
+		return event
+	})
+	flex.SetBorder(true).SetTitle("Store " + formatPath(node.path)).SetTitleAlign(tview.AlignCenter)
+	return flex
+}
+
+func exportForm(node dbNode, dialog string) *tview.Form {
+	form := tview.NewForm().
+		AddTextView("path:", formatPath(node.path), 0, 1, true, false).
+		AddInputField("file:", exportFileName(node.path), 0, nil, nil).
+		AddButton("Cancel", func() {
+			pager.RemovePage(dialog)
+			app.SetFocus(tree)
+		})
+	var export func()
+	export = func() {
+		file := form.GetFormItem(1).(*tview.InputField).GetText()
+		if err := exportFile(file, node.path); err != nil {
+			showRetry(err, export)
+			return
+		}
+		log.Println("exported", node.path, "to", file)
+		pager.RemovePage(dialog)
+		app.SetFocus(tree)
+	}
+	form.AddButton("Export", export)
+	form.SetButtonsAlign(tview.AlignCenter).
+		SetBorder(true).SetTitle("Export to JSON").SetTitleAlign(tview.AlignCenter)
+	return form
+}
+
+func importForm(node dbNode, dialog string) *tview.Form {
+	bucket := node.path
+	if node.kind == "key" {
+		bucket = node.path[:len(node.path)-1]
+	}
+	form := tview.NewForm().
+		AddInputField("file:", "", 0, nil, nil).
+		AddInputField("into bucket:", formatPath(bucket), 0, nil, nil).
+		AddButton("Cancel", func() {
+			pager.RemovePage(dialog)
+			app.SetFocus(tree)
+		})
+	var load func()
+	load = func() {
+		file := form.GetFormItem(0).(*tview.InputField).GetText()
+		path, err := parsePath(form.GetFormItem(1).(*tview.InputField).GetText())
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		entries, err := readExportFile(file)
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		paths := importPaths(path, entries)
+		err = record("import", paths, func() error {
+			return importEntries(path, entries)
+		})
+		if err != nil {
+			showRetry(err, load)
+			return
+		}
+		if len(paths) > 0 {
+			path = paths[0]
+		}
+		reloadAndSetSelection(path)
+		pager.RemovePage(dialog)
+		app.SetFocus(tree)
+	}
+	form.AddButton("Import", load).AddTextView("to import at root", "use empty bucket", 0, 2, true, false)
+	form.SetButtonsAlign(tview.AlignCenter).
+		SetBorder(true).SetTitle("Import from JSON").SetTitleAlign(tview.AlignCenter)
+	return form
+}
+
+func diffForm(node dbNode, dialog string) *tview.Form {
+	bucket := node.path
+	if node.kind == "key" {
+		bucket = node.path[:len(node.path)-1]
+	}
+	form := tview.NewForm().
+		AddInputField("bucket:", formatPath(bucket), 0, nil, nil).
+		AddInputField("compare with file:", old, 0, nil, nil).
+		AddInputField("bucket:", formatPath(bucket), 0, nil, nil).
+		AddButton("Cancel", func() {
+			pager.RemovePage(dialog)
+			app.SetFocus(tree)
+		})
+	form.AddButton("Compare", func() {
+		path, err := parsePath(form.GetFormItem(0).(*tview.InputField).GetText())
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		otherPath, err := parsePath(form.GetFormItem(2).(*tview.InputField).GetText())
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		file := form.GetFormItem(1).(*tview.InputField).GetText()
+		pager.RemovePage(dialog)
+		app.SetFocus(tree)
+		var run func()
+		run = func() {
+			if err := compare(path, file, otherPath); err != nil {
+				showRetry(err, run)
+			}
+		}
+		run()
+	}).AddTextView("", "use empty bucket to compare all buckets", 0, 1, true, false)
+	form.SetButtonsAlign(tview.AlignCenter).
+		SetBorder(true).SetTitle("Compare").SetTitleAlign(tview.AlignCenter)
+	return form
+}
+
+func compactForm(dialog string) *tview.Form {
+	form := tview.NewForm().
+		AddInputField("new file:", compactFileName(old), 0, nil, nil).
+		AddInputField("fill percent:", "100", 4, tview.InputFieldInteger, nil).
+		AddInputField("page size:", strconv.Itoa(dbPageSize()), 8, tview.InputFieldInteger, nil).
+		AddCheckbox("replace open database:", false, nil).
+		AddButton("Cancel", func() {
+			pager.RemovePage(dialog)
+			app.SetFocus(tree)
+		})
+	form.AddButton("Compact", func() {
+		file := form.GetFormItem(0).(*tview.InputField).GetText()
+		fill, pageSize, err := compactOptions(form.GetFormItem(1).(*tview.InputField).GetText(),
+			form.GetFormItem(2).(*tview.InputField).GetText())
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		replace := form.GetFormItem(3).(*tview.Checkbox).IsChecked()
+		if replace && readOnly {
+			showError("database is open read only")
+			return
+		}
+		pager.RemovePage(dialog)
+		report := compactView(file, fill, pageSize, replace)
+		pager.AddPage("compact", report, true, true)
+		app.SetFocus(report)
+	})
+	form.SetButtonsAlign(tview.AlignCenter).
+		SetBorder(true).SetTitle("Compact").SetTitleAlign(tview.AlignCenter)
+	return form
+}
+
+func backupForm(dialog string) *tview.Form {
+	form := tview.NewForm().
+		AddInputField("file:", backupFileName(old, time.Now()), 0, nil, nil).
+		AddButton("Cancel", func() {
+			pager.RemovePage(dialog)
+			app.SetFocus(tree)
+		})
+	var backup func()
+	backup = func() {
+		file := form.GetFormItem(0).(*tview.InputField).GetText()
+		if err := backupDatabase(file); err != nil {
+			showRetry(err, backup)
+			return
+		}
+		log.Println("backup of", old, "written to", file)
+		pager.RemovePage(dialog)
+		app.SetFocus(tree)
+		showInfo(fmt.Sprintf("backup written to %s (%d bytes)", file, fileSize(file)))
+	}
+	form.AddButton("Backup", backup)
+	form.SetButtonsAlign(tview.AlignCenter).
+		SetBorder(true).SetTitle("Backup").SetTitleAlign(tview.AlignCenter)
 	return form
 }
 
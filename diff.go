@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// difference is a key or bucket that was added, removed or changed between two buckets.
+// Values are kept for keys so they can be shown side by side.
+type difference struct {
+	path     []string
+	change   string
+	bucket   bool
+	oldValue []byte
+	newValue []byte
+}
+
+// container is implemented by both bbolt.Tx (the root) and bbolt.Bucket.
+type container interface {
+	Cursor() *bbolt.Cursor
+	Bucket(name []byte) *bbolt.Bucket
+}
+
+// openOther opens a second database read-only for comparison. The open database
+// is returned if file refers to it, in which case close is a no-op.
+func openOther(file string) (*bbolt.DB, func(), error) {
+	current, _ := filepath.Abs(old)
+	other, _ := filepath.Abs(file)
+	if db != nil && current == other {
+		return db, func() {}, nil
+	}
+	otherDB, err := bbolt.Open(file, 0o666, openOptions(true))
+	if err != nil {
+		return nil, nil, err
+	}
+	return otherDB, func() { otherDB.Close() }, nil
+}
+
+func getContainer(path []string, tx *bbolt.Tx) (container, error) { //nolint:ireturn
+	if len(path) == 0 {
+		return tx, nil
+	}
+	return getBucket(path, tx)
+}
+
+// diffDatabases compares the bucket at oldPath in oldDB with the bucket at newPath in newDB.
+// An empty path compares the root buckets. Paths of differences are relative to the compared buckets.
+func diffDatabases(oldDB *bbolt.DB, oldPath []string, newDB *bbolt.DB, newPath []string) ([]difference, error) {
+	diffs := []difference{}
+	err := oldDB.View(func(oldTx *bbolt.Tx) error {
+		return newDB.View(func(newTx *bbolt.Tx) error {
+			oldBucket, err := getContainer(oldPath, oldTx)
+			if err != nil {
+				return fmt.Errorf("%s: %w", formatPath(oldPath), err)
+			}
+			newBucket, err := getContainer(newPath, newTx)
+			if err != nil {
+				return fmt.Errorf("%s: %w", formatPath(newPath), err)
+			}
+			return diffBuckets(oldBucket, newBucket, nil, &diffs)
+		})
+	})
+	return diffs, err
+}
+
+// diffBuckets walks both buckets in key order. Added and removed buckets are reported
+// as a whole; buckets present in both are compared recursively.
+func diffBuckets(oldBucket, newBucket container, path []string, diffs *[]difference) error {
+	oldCursor, newCursor := oldBucket.Cursor(), newBucket.Cursor()
+	oldKey, oldValue := oldCursor.First()
+	newKey, newValue := newCursor.First()
+	for oldKey != nil || newKey != nil {
+		order := 0
+		switch {
+		case newKey == nil:
+			order = -1
+		case oldKey == nil:
+			order = 1
+		default:
+			order = bytes.Compare(oldKey, newKey)
+		}
+		switch {
+		case order < 0:
+			*diffs = append(*diffs, difference{
+				path: append(slices.Clone(path), string(oldKey)), change: "removed",
+				bucket: oldValue == nil, oldValue: slices.Clone(oldValue),
+			})
+			oldKey, oldValue = oldCursor.Next()
+		case order > 0:
+			*diffs = append(*diffs, difference{
+				path: append(slices.Clone(path), string(newKey)), change: "added",
+				bucket: newValue == nil, newValue: slices.Clone(newValue),
+			})
+			newKey, newValue = newCursor.Next()
+		default:
+			childPath := append(slices.Clone(path), string(newKey))
+			switch {
+			case oldValue == nil && newValue == nil:
+				if err := diffBuckets(oldBucket.Bucket(oldKey), newBucket.Bucket(newKey), childPath, diffs); err != nil {
+					return err
+				}
+			case oldValue == nil || newValue == nil || !bytes.Equal(oldValue, newValue):
+				*diffs = append(*diffs, difference{
+					path: childPath, change: "changed", bucket: newValue == nil,
+					oldValue: slices.Clone(oldValue), newValue: slices.Clone(newValue),
+				})
+			}
+			oldKey, oldValue = oldCursor.Next()
+			newKey, newValue = newCursor.Next()
+		}
+	}
+	return nil
+}
+
+func diffColor(change string) tcell.Color {
+	switch change {
+	case "added":
+		return tcell.ColorGreen
+	case "removed":
+		return tcell.ColorRed
+	default:
+		return tcell.ColorYellow
+	}
+}
+
+// diffView shows the differences in a tree colored by change: added green, removed
+// red and changed yellow. The details pane shows old and new values of keys.
+func diffView(title string, diffs []difference) *tview.Grid {
+	detail := tview.NewTextView()
+	detail.SetBorder(true).SetTitle("Values")
+	root := tview.NewTreeNode(".").SetColor(tcell.ColorRed)
+	for i, diff := range diffs {
+		node := root
+		for j, name := range diff.path {
+			child := diffChild(node, name)
+			if child == nil {
+				child = tview.NewTreeNode(tview.Escape(formatName([]byte(name))))
+				if j == len(diff.path)-1 {
+					child.SetReference(i).SetColor(diffColor(diff.change))
+				}
+				node.AddChild(child)
+			}
+			node = child
+		}
+	}
+	diffTree := tview.NewTreeView().SetRoot(root).SetCurrentNode(root)
+	diffTree.SetChangedFunc(func(node *tview.TreeNode) {
+		i, ok := node.GetReference().(int)
+		if !ok {
+			detail.SetText("")
+			return
+		}
+		diff := diffs[i]
+		kind := "key"
+		if diff.bucket {
+			kind = "bucket"
+		}
+		text := fmt.Sprintf("%s %s\n\nPath: %s\n", kind, diff.change, displayPath(diff.path))
+		if diff.oldValue != nil {
+			text += fmt.Sprintf("\nOld value:\n\n%s\n", formatValue(diff.oldValue))
+		}
+		if diff.newValue != nil {
+			text += fmt.Sprintf("\nNew value:\n\n%s\n", formatValue(diff.newValue))
+		}
+		detail.SetText(text)
+	})
+	diffTree.SetBorder(true).SetTitle(fmt.Sprintf("%d differences", len(diffs)))
+	grid := tview.NewGrid().
+		SetRows(1, 0, 1).
+		SetColumns(0, 0).
+		AddItem(textView(title), 0, 0, 1, 2, 0, 0, false).
+		AddItem(diffTree, 1, 0, 1, 1, 0, 0, true).
+		AddItem(detail, 1, 1, 1, 1, 0, 0, false).
+		AddItem(textView("green: added, red: removed, yellow: changed, esc to close"), 2, 0, 1, 2, 0, 0, false)
+	grid.SetBorder(true)
+	return grid
+}
+
+// diffChild returns the child of node for the named intermediate bucket.
+func diffChild(node *tview.TreeNode, name string) *tview.TreeNode {
+	for _, child := range node.GetChildren() {
+		if child.GetText() == tview.Escape(formatName([]byte(name))) && child.GetReference() == nil {
+			return child
+		}
+	}
+	return nil
+}
+
+// compare shows the changes from the bucket at otherPath in another file (or the open
+// database) to the bucket at path in the open database.
+func compare(path []string, otherFile string, otherPath []string) error {
+	other, closeOther, err := openOther(otherFile)
+	if err != nil {
+		return err
+	}
+	defer closeOther()
+	var diffs []difference
+	err = useDB(false, func(d *bbolt.DB) error {
+		diffs, err = diffDatabases(other, otherPath, d, path)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		return errors.New("no differences")
+	}
+	title := fmt.Sprintf("changes from %s [%s] to %s [%s]", otherFile, formatPath(otherPath), old, formatPath(path))
+	view := diffView(title, diffs)
+	pager.AddPage("diff", view, true, true)
+	app.SetFocus(view)
+	return nil
+}
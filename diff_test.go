@@ -0,0 +1,106 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+// testDB creates a database file holding the entries in root buckets.
+func testDB(t *testing.T, entries ...*entry) *bbolt.DB {
+	t.Helper()
+	d, err := bbolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o666, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	err = d.Update(func(tx *bbolt.Tx) error {
+		for _, e := range entries {
+			if err := writeEntry([]string{string(e.name)}, e, tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func bucketEntry(name string, children ...*entry) *entry {
+	return &entry{name: []byte(name), bucket: true, children: children}
+}
+
+func keyEntry(name, value string) *entry {
+	return &entry{name: []byte(name), value: []byte(value)}
+}
+
+func TestDiffDatabases(t *testing.T) {
+	oldDB := testDB(t,
+		bucketEntry("a",
+			keyEntry("same", "1"),
+			keyEntry("changed", "old"),
+			keyEntry("removed", "gone"),
+			keyEntry("to-bucket", "key"),
+			bucketEntry("nested", keyEntry("k", "1")),
+			bucketEntry("dropped", keyEntry("k", "1")),
+		),
+		bucketEntry("only-old"),
+		bucketEntry("same", keyEntry("k", "v")),
+	)
+	newDB := testDB(t,
+		bucketEntry("a",
+			keyEntry("added", "new"),
+			keyEntry("changed", "new"),
+			keyEntry("same", "1"),
+			bucketEntry("to-bucket"),
+			bucketEntry("nested", keyEntry("k", "2")),
+		),
+		bucketEntry("b", keyEntry("same", "1")),
+		bucketEntry("same", keyEntry("k", "v")),
+	)
+	tests := []struct {
+		name             string
+		oldPath, newPath []string
+		want             []difference
+	}{
+		{"databases", nil, nil, []difference{
+			{path: []string{"a", "added"}, change: "added", newValue: []byte("new")},
+			{path: []string{"a", "changed"}, change: "changed", oldValue: []byte("old"), newValue: []byte("new")},
+			{path: []string{"a", "dropped"}, change: "removed", bucket: true},
+			{path: []string{"a", "nested", "k"}, change: "changed", oldValue: []byte("1"), newValue: []byte("2")},
+			{path: []string{"a", "removed"}, change: "removed", oldValue: []byte("gone")},
+			{path: []string{"a", "to-bucket"}, change: "changed", bucket: true, oldValue: []byte("key")},
+			{path: []string{"b"}, change: "added", bucket: true},
+			{path: []string{"only-old"}, change: "removed", bucket: true},
+		}},
+		{"buckets", []string{"a", "nested"}, []string{"a", "nested"}, []difference{
+			{path: []string{"k"}, change: "changed", oldValue: []byte("1"), newValue: []byte("2")},
+		}},
+		{"different buckets", []string{"a"}, []string{"b"}, []difference{
+			{path: []string{"changed"}, change: "removed", oldValue: []byte("old")},
+			{path: []string{"dropped"}, change: "removed", bucket: true},
+			{path: []string{"nested"}, change: "removed", bucket: true},
+			{path: []string{"removed"}, change: "removed", oldValue: []byte("gone")},
+			{path: []string{"to-bucket"}, change: "removed", oldValue: []byte("key")},
+		}},
+		{"equal", []string{"same"}, []string{"same"}, []difference{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := diffDatabases(oldDB, test.oldPath, newDB, test.newPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("diffDatabases() =\n%+v\nwant\n%+v", got, test.want)
+			}
+		})
+	}
+	if _, err := diffDatabases(oldDB, []string{"missing"}, newDB, nil); err == nil {
+		t.Error("diffDatabases() with missing bucket succeeded")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// displayMode selects how names and values are shown in the tree and details pane.
+type displayMode int
+
+const (
+	displayAuto displayMode = iota
+	displayText
+	displayEscaped
+	displayHex
+	displayBase64
+)
+
+var display = displayAuto
+
+func (m displayMode) String() string {
+	return [...]string{"auto", "utf-8", "escaped", "hex", "base64"}[m]
+}
+
+// nextDisplayMode cycles through the display modes.
+func nextDisplayMode() {
+	display = (display + 1) % (displayBase64 + 1)
+}
+
+// printable reports whether data is valid utf-8 without control characters.
+// Newlines and tabs are accepted if multiline is set.
+func printable(data []byte, multiline bool) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if multiline && (r == '\n' || r == '\t' || r == '\r') {
+			continue
+		}
+		if !unicode.IsPrint(r) && r != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// sanitize replaces invalid utf-8 and control characters so text cannot disturb the terminal.
+func sanitize(data []byte) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' || unicode.IsPrint(r) || r == ' ' {
+			return r
+		}
+		return utf8.RuneError
+	}, strings.ToValidUTF8(string(data), string(utf8.RuneError)))
+}
+
+// formatName returns a single line representation of a key or bucket name.
+// In auto mode names that are not printable are shown as escaped go strings.
+func formatName(name []byte) string {
+	switch display {
+	case displayText:
+		return strings.ReplaceAll(sanitize(name), "\n", string(utf8.RuneError))
+	case displayEscaped:
+		return strconv.Quote(string(name))
+	case displayHex:
+		return hex.EncodeToString(name)
+	case displayBase64:
+		return base64.StdEncoding.EncodeToString(name)
+	default:
+		if printable(name, false) {
+			return string(name)
+		}
+		return strconv.Quote(string(name))
+	}
+}
+
+// formatValue returns the representation of a value for the details pane.
+// In auto mode printable values are shown as (indented) text and others as a hex dump.
+func formatValue(value []byte) string {
+	switch display {
+	case displayText:
+		return sanitize([]byte(prettyString(value)))
+	case displayEscaped:
+		return strconv.Quote(string(value))
+	case displayHex:
+		return hex.Dump(value)
+	case displayBase64:
+		return base64.StdEncoding.EncodeToString(value)
+	default:
+		if printable(value, true) {
+			return prettyString(value)
+		}
+		return hex.Dump(value)
+	}
+}
+
+// displayPath formats each element of path with formatName.
+func displayPath(path []string) string {
+	names := make([]string, 0, len(path))
+	for _, name := range path {
+		names = append(names, formatName([]byte(name)))
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// editorCommand is $VISUAL or $EDITOR, falling back to vi.
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return []string{"vi"}
+}
+
+// runEditor suspends the application and edits text in the external editor.
+func runEditor(text string, isJSON bool) (string, error) {
+	pattern := "bboltEdit-*.txt"
+	if isJSON {
+		pattern = "bboltEdit-*.json"
+	}
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	editor := editorCommand()
+	var runErr error
+	app.Suspend(func() {
+		cmd := exec.Command(editor[0], append(editor[1:], file.Name())...) //nolint:gosec
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		runErr = cmd.Run()
+	})
+	if runErr != nil {
+		return "", errors.New(strings.Join(editor, " ") + ": " + runErr.Error())
+	}
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	result := string(edited)
+	// most editors add a final newline
+	if !strings.HasSuffix(text, "\n") {
+		result = strings.TrimSuffix(result, "\n")
+	}
+	return result, nil
+}
+
+// editExternal edits the value of a key in the external editor. JSON values must still
+// be valid JSON afterwards, otherwise the user can edit again or store the text anyway.
+func editExternal(node dbNode) {
+	mode := detectStorageMode(node.value)
+	original := editText(node.value, mode)
+	isJSON := json.Valid(node.value)
+	var edit func(text string)
+	edit = func(text string) {
+		edited, err := runEditor(text, isJSON)
+		switch {
+		case err != nil:
+			showError(err.Error())
+		case edited == original:
+			showInfo("value unchanged")
+		case isJSON && !json.Valid([]byte(edited)):
+			retry := tview.NewModal().
+				SetText("the value is no longer valid JSON").
+				AddButtons([]string{"Edit again", "Store as text", "Cancel"}).
+				SetDoneFunc(func(_ int, label string) {
+					pager.RemovePage("retry")
+					app.SetFocus(tree)
+					switch label {
+					case "Edit again":
+						edit(edited)
+					case "Store as text":
+						showStoreForm(node, edited, storeRaw, "dialog")
+					}
+				})
+			pager.AddPage("retry", retry, true, true)
+			app.SetFocus(retry)
+		default:
+			showStoreForm(node, edited, mode, "dialog")
+		}
+	}
+	edit(original)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"go.etcd.io/bbolt"
+)
+
+// exportDoc is the json document written by export. Items mirror the bucket hierarchy.
+type exportDoc struct {
+	Path  []string     `json:"path"`
+	Items []exportItem `json:"items"`
+}
+
+// exportItem is a key or bucket. Names are stored as text when they are valid utf-8 and
+// as base64 otherwise. Values are embedded as json if they are compact json that is imported unchanged, stored as text
+// if they are valid utf-8 and as base64 otherwise, so that export and import round-trip
+// keys and values byte for byte.
+type exportItem struct {
+	Key       string          `json:"key,omitempty"`
+	KeyBase64 string          `json:"keyBase64,omitempty"`
+	Bucket    bool            `json:"bucket,omitempty"`
+	Items     []exportItem    `json:"items,omitempty"`
+	JSON      json.RawMessage `json:"json,omitempty"`
+	Text      *string         `json:"text,omitempty"`
+	Base64    *string         `json:"base64,omitempty"`
+}
+
+// exportEntries reads the key or bucket at path, or all root buckets if path is empty.
+func exportEntries(path []string) ([]*entry, error) {
+	entries := []*entry{}
+	err := view(func(tx *bbolt.Tx) error {
+		if len(path) == 0 {
+			return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+				e, err := copyEntry(name, b)
+				if err != nil {
+					return err
+				}
+				entries = append(entries, e)
+				return nil
+			})
+		}
+		e, err := readEntry(path, tx)
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			return errors.New("invalid path: not found")
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// writeExport writes the key or bucket at path (or the whole database) as json.
+func writeExport(w io.Writer, path []string) error {
+	entries, err := exportEntries(path)
+	if err != nil {
+		return err
+	}
+	return encodeExport(w, exportDoc{Path: path, Items: toExportItems(entries)})
+}
+
+func encodeExport(w io.Writer, doc exportDoc) error {
+	if doc.Path == nil {
+		doc.Path = []string{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+func exportFile(file string, path []string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := writeExport(f, path); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exportFileName is the default file name for exporting the node at path.
+func exportFileName(path []string) string {
+	if len(path) == 0 {
+		return strings.TrimSuffix(filepath.Base(old), filepath.Ext(old)) + ".json"
+	}
+	return filepath.Base(path[len(path)-1]) + ".json"
+}
+
+// readExport decodes an export document.
+func readExport(r io.Reader) ([]*entry, error) {
+	var doc exportDoc
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return fromExportItems(doc.Items)
+}
+
+func readExportFile(file string) ([]*entry, error) {
+	if file == "-" {
+		return readExport(os.Stdin)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readExport(f)
+}
+
+// importPaths are the paths the entries are written to when imported into the bucket at path.
+func importPaths(path []string, entries []*entry) [][]string {
+	paths := [][]string{}
+	for _, e := range entries {
+		paths = append(paths, append(slices.Clone(path), string(e.name)))
+	}
+	return paths
+}
+
+// importEntries writes the entries into the bucket at path, creating it as required.
+// Existing buckets are merged and existing keys are overwritten.
+func importEntries(path []string, entries []*entry) error {
+	return update(func(tx *bbolt.Tx) error {
+		for i, p := range importPaths(path, entries) {
+			if err := writeEntry(p, entries[i], tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func toExportItems(entries []*entry) []exportItem {
+	items := []exportItem{}
+	for _, e := range entries {
+		item := exportItem{}
+		if utf8.Valid(e.name) {
+			item.Key = string(e.name)
+		} else {
+			item.KeyBase64 = base64.StdEncoding.EncodeToString(e.name)
+		}
+		if e.bucket {
+			item.Bucket = true
+			item.Items = toExportItems(e.children)
+			items = append(items, item)
+			continue
+		}
+		switch {
+		case jsonRoundTrips(e.value):
+			item.JSON = e.value
+		case utf8.Valid(e.value):
+			text := string(e.value)
+			item.Text = &text
+		default:
+			encoded := base64.StdEncoding.EncodeToString(e.value)
+			item.Base64 = &encoded
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// jsonRoundTrips reports whether value is imported unchanged when embedded as json. The
+// encoder escapes some characters, e.g. U+2028, which import would keep escaped.
+func jsonRoundTrips(value []byte) bool {
+	var compact, encoded bytes.Buffer
+	if !utf8.Valid(value) || !json.Valid(value) || json.Compact(&compact, value) != nil || !bytes.Equal(compact.Bytes(), value) {
+		return false
+	}
+	if encodeExport(&encoded, exportDoc{Items: []exportItem{{JSON: value}}}) != nil {
+		return false
+	}
+	var doc exportDoc
+	if json.Unmarshal(encoded.Bytes(), &doc) != nil || len(doc.Items) != 1 {
+		return false
+	}
+	compact.Reset()
+	return json.Compact(&compact, doc.Items[0].JSON) == nil && bytes.Equal(compact.Bytes(), value)
+}
+
+func fromExportItems(items []exportItem) ([]*entry, error) {
+	entries := []*entry{}
+	for _, item := range items {
+		e := &entry{name: []byte(item.Key), bucket: item.Bucket}
+		if item.KeyBase64 != "" {
+			name, err := base64.StdEncoding.DecodeString(item.KeyBase64)
+			if err != nil {
+				return nil, err
+			}
+			e.name = name
+		}
+		if len(e.name) == 0 {
+			return nil, errors.New("invalid import: item without key")
+		}
+		if item.Bucket {
+			children, err := fromExportItems(item.Items)
+			if err != nil {
+				return nil, err
+			}
+			e.children = children
+			entries = append(entries, e)
+			continue
+		}
+		switch {
+		case item.JSON != nil:
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, item.JSON); err != nil {
+				return nil, err
+			}
+			e.value = compact.Bytes()
+		case item.Text != nil:
+			e.value = []byte(*item.Text)
+		case item.Base64 != nil:
+			value, err := base64.StdEncoding.DecodeString(*item.Base64)
+			if err != nil {
+				return nil, err
+			}
+			e.value = value
+		default:
+			return nil, errors.New("invalid import: key " + item.Key + " has no value")
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
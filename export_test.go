@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestExportRoundTrip(t *testing.T) {
+	values := map[string][]byte{
+		"compact json":   []byte(`{"a":[1,2,{"b":null}]}`),
+		"html":           []byte(`{"a":"<b>&</b>"}`),
+		"line separator": []byte("{\"a\":\"<\u2028>\"}"),
+		"para separator": []byte("\"\u2029\""),
+		"escaped":        []byte(`"é"`),
+		"pretty json":    []byte("{\n  \"a\": 1\n}"),
+		"text":           []byte("hello world"),
+		"number text":    []byte("007"),
+		"binary":         {0x00, 0xff, 0xfe},
+		"invalid utf-8":  []byte("\"\xff\""),
+	}
+	for name, value := range values {
+		t.Run(name, func(t *testing.T) {
+			entries := []*entry{{
+				name:   []byte("bucket"),
+				bucket: true,
+				children: []*entry{
+					{name: []byte("key"), value: value},
+					{name: []byte{0x00, 0xc3}, value: value},
+				},
+			}}
+			var buf bytes.Buffer
+			if err := encodeExport(&buf, exportDoc{Items: toExportItems(entries)}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readExport(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, entries) {
+				t.Errorf("got %q, want %q", got[0].children[0].value, value)
+			}
+		})
+	}
+}
+
+func TestJSONRoundTrips(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{`{"a":1}`, true},
+		{`"<&>"`, true},
+		{"\"\u2028\"", false},
+		{`{"a": 1}`, false},
+		{`007`, false},
+		{"\"\xff\"", false},
+	}
+	for _, test := range tests {
+		if got := jsonRoundTrips([]byte(test.value)); got != test.want {
+			t.Errorf("jsonRoundTrips(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
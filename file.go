@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
@@ -21,12 +22,6 @@ var (
 )
 
 func newFiles() *tview.Grid { //nolint:funlen
-	rightKeys := []key{
-		{"o", "open dialog to change directory"},
-		{"p", "println node table to logs"},
-		{"enter", "expand dir, select file"},
-		{"?", "show this help"},
-	}
 	cwd, _ := os.Getwd()
 	picker := fileTree(cwd)
 	top = textView("Select file to view (" + cwd + ")")
@@ -41,43 +36,50 @@ func newFiles() *tview.Grid { //nolint:funlen
 		AddItem(picker, 1, 0, 1, 1, 0, 0, true)
 	fileGrid.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		log.Println("file grid handler", event.Key(), event.Modifiers(), event.Rune())
-		switch event.Key() {
-		case tcell.KeyRune:
-			switch event.Rune() {
-			case 'o':
-				selected := picker.GetCurrentNode().GetReference().(ref).path
-				dirsearch := modal(dirForm("dir", selected, newRootDir), 40, 10)
-				pager.AddPage("dir", dirsearch, true, true)
-				pager.SendToFront("dir")
-			case 'p':
-				current := picker.GetRoot()
-				log.Println("root", current.GetText())
-				for _, child := range current.GetChildren() {
-					log.Println("child", child.GetText())
-				}
-			case '?':
-				help := helpDialog("Key Bindings", 100, 10, rightKeys, treeMoveKeys)
-				pager.AddPage("help", help, true, true)
-				app.SetFocus(help)
+		bound := actionFor("file", event)
+		if bound == nil {
+			return event
+		}
+		switch bound.id {
+		case "file-change-dir":
+			selected := picker.GetCurrentNode().GetReference().(ref).path
+			dirsearch := modal(dirForm("dir", selected, newRootDir), 40, 10)
+			pager.AddPage("dir", dirsearch, true, true)
+			pager.SendToFront("dir")
+		case "file-print":
+			current := picker.GetRoot()
+			log.Println("root", current.GetText())
+			for _, child := range current.GetChildren() {
+				log.Println("child", child.GetText())
+			}
+		case "file-open-read-only":
+			r := picker.GetCurrentNode().GetReference()
+			if r == nil || r.(ref).isDir {
 				return nil
 			}
-
-		case tcell.KeyEnter:
+			openFile(r.(ref).path, true, false)
+			return nil
+		case "file-open-tab":
+			r := picker.GetCurrentNode().GetReference()
+			if r == nil || r.(ref).isDir {
+				return nil
+			}
+			openFile(r.(ref).path, openReadOnly, true)
+			return nil
+		case "file-help":
+			keys := keyTable("file")
+			help := helpDialog("Key Bindings", 100, len(keys)+4, keys, treeMoveKeys)
+			pager.AddPage("help", help, true, true)
+			app.SetFocus(help)
+			return nil
+		case "file-open":
 			r := picker.GetCurrentNode().GetReference()
 			if r == nil {
 				return nil
 			}
 			node := r.(ref)
 			if !node.isDir {
-				log.Println("selected file", node.path)
-				if err := InitDatabase(node.path); err != nil {
-					showError(err.Error())
-					return nil
-				}
-				tree = newTree(details)
-				grid = mainGrid()
-				pager.AddPage("main", grid, true, true).RemovePage("file")
-				app.SetFocus(tree)
+				openFile(node.path, openReadOnly, false)
 				return nil
 			}
 		}
@@ -116,6 +118,36 @@ func newFiles() *tview.Grid { //nolint:funlen
 	return fileGrid
 }
 
+// openFile replaces the database in the main window with the selected file, or opens
+// it in a new tab.
+func openFile(path string, ro, newTab bool) {
+	log.Println("selected file", path, "read only", ro, "new tab", newTab)
+	open := InitDatabase
+	if newTab {
+		open = openTab
+	} else {
+		leaveDualPane()
+	}
+	if err := open(path, ro); err != nil {
+		if errors.Is(err, errLocked) {
+			showLocked(path, ro, newTab, err)
+			return
+		}
+		if errors.Is(err, errNoDatabase) {
+			pager.AddPage("dialog", modal(newDatabaseForm(path, false, "dialog"), 60, 11), true, true)
+			return
+		}
+		showError(err.Error())
+		return
+	}
+	pager.RemovePage("file")
+	if newTab {
+		return
+	}
+	tree = newTree(details)
+	showMain()
+}
+
 func fileTree(dir string) *tview.TreeView {
 	rootDir := ".."
 	root := tview.NewTreeNode(rootDir).
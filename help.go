@@ -10,7 +10,9 @@ type key struct {
 	help string
 }
 
+// treeMoveKeys are handled by the tree views and cannot be changed.
 var treeMoveKeys = []key{
+	{"Enter", "expand or colapse node"},
 	{"j,↓,→ ", "move selection down by one node"},
 	{"k,↑,←", "move selection up by one node"},
 	{"g, home", "move selection to top node"},
@@ -20,12 +22,9 @@ var treeMoveKeys = []key{
 	{"Esc", "Close window/dialog"},
 }
 
-var mainKeys = []key{
-	{"F1", "show about/help"},
-	{"Esc", "close dialog/application"},
-	{"Ctrl-Q", "close application"},
-	{"", ""},
-	{"?", "detailed help for a window"},
+// treeMoveKeyNames are the names of the keys of treeMoveKeys that tree actions would shadow.
+var treeMoveKeyNames = []string{
+	"Enter", "j", "Down", "Right", "k", "Up", "Left", "g", "Home", "G", "End", "Ctrl-F", "PgDn", "Ctrl-B", "PgUp",
 }
 
 func helpDialog(title string, width, height int, right, left []key) tview.Primitive { //nolint:ireturn
@@ -54,6 +53,8 @@ func helpDialog(title string, width, height int, right, left []key) tview.Primit
 
 func about(w, h int) tview.Primitive { //nolint:ireturn,varnamelen
 	table := tview.NewTable()
+	mainKeys := append(keyTable("app"), key{"Esc", "close dialog/application"}, key{"", ""},
+		key{boundKey("help"), "detailed help for a window"})
 	for i, key := range mainKeys {
 		table.SetCell(i, 0, tview.NewTableCell(key.name).
 			SetAlign(tview.AlignCenter).SetExpansion(1).SetTextColor(tcell.ColorGrey))
@@ -87,3 +88,11 @@ func showError(message string) {
 	pager.AddPage("error", dialog, true, true)
 	app.SetFocus(dialog)
 }
+
+// showInfo reports the result of an operation that has no other visible effect.
+func showInfo(message string) {
+	dialog := errorView(message).SetBackgroundColor(tcell.ColorDarkGreen)
+	dialog.SetTitle("Info")
+	pager.AddPage("error", dialog, true, true)
+	app.SetFocus(dialog)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// action is something that can be done with a key in one of the windows. key is a single
+// character or a key name like Ctrl-R, F5 or Tab; the default can be changed in the config
+// file with key.<id> = <key>.
+type action struct {
+	window string
+	id     string
+	key    string
+	help   string
+	// mutating actions are disabled when the database is open read only
+	mutating bool
+}
+
+// actions are listed in the order they are shown in the help of their window.
+var actions = []*action{
+	{window: "app", id: "about", key: "F1", help: "show about/help"},
+	{window: "app", id: "quit-now", key: "Ctrl-Q", help: "close application"},
+
+	{window: "tree", id: "copy", key: "c", help: "copy key or bucket", mutating: true},
+	{window: "tree", id: "add-bucket", key: "b", help: "create new bucket", mutating: true},
+	{window: "tree", id: "delete", key: "d", help: "delete key or bucket", mutating: true},
+	{window: "tree", id: "edit", key: "e", help: "empty bucket or edit key", mutating: true},
+	{window: "tree", id: "edit-external", key: "E", help: "edit key in $VISUAL or $EDITOR", mutating: true},
+	{window: "tree", id: "add-key", key: "a", help: "add new key", mutating: true},
+	{window: "tree", id: "move", key: "m", help: "move key or bucket", mutating: true},
+	{window: "tree", id: "open", key: "o", help: "open file selection"},
+	{window: "tree", id: "rename", key: "r", help: "rename key or bucket", mutating: true},
+	{window: "tree", id: "search", key: "s", help: "search for key or bucket"},
+	{window: "tree", id: "find", key: "f", help: "find names or values matching text or regex"},
+	{window: "tree", id: "next-match", key: "n", help: "go to next match"},
+	{window: "tree", id: "previous-match", key: "N", help: "go to previous match"},
+	{window: "tree", id: "find-results", key: "F", help: "show find results"},
+	{window: "tree", id: "import", key: "i", help: "import keys and buckets from json", mutating: true},
+	{window: "tree", id: "export", key: "X", help: "export key, bucket or database to json"},
+	{window: "tree", id: "diff", key: "D", help: "diff bucket with another bucket or database"},
+	{window: "tree", id: "stats", key: "S", help: "show database and bucket statistics"},
+	{window: "tree", id: "check", key: "C", help: "check database integrity"},
+	{window: "tree", id: "compact", key: "Z", help: "compact database into a new file"},
+	{window: "tree", id: "backup", key: "B", help: "write a backup of the database"},
+	{window: "tree", id: "new-database", key: "W", help: "create a new database file"},
+	{window: "tree", id: "previous-tab", key: "[", help: "show previous database tab"},
+	{window: "tree", id: "next-tab", key: "]", help: "show next database tab"},
+	{window: "tree", id: "close-tab", key: "q", help: "close database tab"},
+	{window: "tree", id: "two-pane", key: "p", help: "start or leave two-pane mode"},
+	{window: "tree", id: "switch-pane", key: "Tab", help: "focus details, or the other pane in two-pane mode"},
+	{window: "tree", id: "pane-copy", key: "F5", help: "copy key or bucket to the other pane"},
+	{window: "tree", id: "pane-move", key: "F6", help: "move key or bucket to the other pane"},
+	{window: "tree", id: "restore", key: "R", help: "restore key or bucket from a snapshot"},
+	{window: "tree", id: "display-mode", key: "v", help: "change view mode: auto, utf-8, escaped, hex, base64"},
+	{window: "tree", id: "transaction", key: "t", help: "start transaction mode, or commit or discard changes", mutating: true},
+	{window: "tree", id: "undo", key: "u", help: "undo last change", mutating: true},
+	{window: "tree", id: "redo", key: "U", help: "redo last undone change", mutating: true},
+	{window: "tree", id: "command-line", key: ":", help: "enter a command, tab completes paths and file names"},
+	{window: "tree", id: "command-palette", key: "Ctrl-P", help: "search actions and commands"},
+	{window: "tree", id: "help", key: "?", help: "show help"},
+	{window: "tree", id: "reload", key: "Ctrl-R", help: "reload database"},
+	{window: "tree", id: "collapse-all", key: "Ctrl-C", help: "collapse all nodes"},
+	{window: "tree", id: "expand-all", key: "Ctrl-X", help: "expand all nodes"},
+	{window: "tree", id: "quit", key: "Esc", help: "close application"},
+
+	{window: "file", id: "file-open", key: "Enter", help: "expand dir, select file"},
+	{window: "file", id: "file-open-read-only", key: "r", help: "open selected file read only"},
+	{window: "file", id: "file-open-tab", key: "t", help: "open selected file in a new tab"},
+	{window: "file", id: "file-change-dir", key: "o", help: "open dialog to change directory"},
+	{window: "file", id: "file-print", key: "p", help: "println node table to logs"},
+	{window: "file", id: "file-help", key: "?", help: "show this help"},
+}
+
+// keyName is the name of the key of event as used in the bindings.
+func keyName(event *tcell.EventKey) string {
+	if event.Key() == tcell.KeyRune {
+		return string(event.Rune())
+	}
+	return tcell.KeyNames[event.Key()]
+}
+
+// actionFor returns the action of window bound to the key of event, or nil.
+func actionFor(window string, event *tcell.EventKey) *action {
+	name := keyName(event)
+	for _, a := range actions {
+		if a.window == window && a.key == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// bindKey binds the action id to a key. Application wide actions are also handled in
+// input fields, so they cannot be bound to a character.
+func bindKey(id, name string) error {
+	index := slices.IndexFunc(actions, func(a *action) bool { return a.id == id })
+	if index < 0 {
+		return errors.New("unknown action " + id)
+	}
+	if len([]rune(name)) == 1 {
+		if actions[index].window == "app" {
+			return fmt.Errorf("%s needs a key that is not a character, like F2 or Ctrl-O", id)
+		}
+	} else {
+		known := false
+		for _, keyName := range tcell.KeyNames {
+			if strings.EqualFold(name, keyName) {
+				name, known = keyName, true
+				break
+			}
+		}
+		if !known {
+			return errors.New("unknown key " + name)
+		}
+	}
+	actions[index].key = name
+	return nil
+}
+
+// checkBindings reports keys bound to more than one action of a window, and keys of the
+// main window that are taken by an application wide action or by the tree movement.
+func checkBindings() error {
+	bound := map[string]string{}
+	for _, a := range actions {
+		if other, ok := bound[a.window+" "+a.key]; ok {
+			return fmt.Errorf("key %s is bound to both %s and %s", a.key, other, a.id)
+		}
+		bound[a.window+" "+a.key] = a.id
+	}
+	for _, a := range actions {
+		if a.window == "app" {
+			continue
+		}
+		if other, ok := bound["app "+a.key]; ok {
+			return fmt.Errorf("key %s is bound to both %s and %s", a.key, other, a.id)
+		}
+		if a.window == "tree" && slices.Contains(treeMoveKeyNames, a.key) {
+			return fmt.Errorf("key %s of %s is used to move in the tree", a.key, a.id)
+		}
+	}
+	return nil
+}
+
+// boundKey is the key of action id.
+func boundKey(id string) string {
+	for _, a := range actions {
+		if a.id == id {
+			return a.key
+		}
+	}
+	return ""
+}
+
+// runAction runs the tree action id as if its key was pressed.
+func runAction(id string) {
+	name := boundKey(id)
+	var event *tcell.EventKey
+	if runes := []rune(name); len(runes) == 1 {
+		event = tcell.NewEventKey(tcell.KeyRune, runes[0], tcell.ModNone)
+	} else {
+		for key, keyName := range tcell.KeyNames {
+			if keyName == name {
+				event = tcell.NewEventKey(key, 0, tcell.ModNone)
+				break
+			}
+		}
+	}
+	if event != nil {
+		tree.GetInputCapture()(event)
+	}
+}
+
+// keyTable lists the bindings of window for the help dialogs.
+func keyTable(window string) []key {
+	keys := []key{}
+	for _, a := range actions {
+		if a.window == window {
+			keys = append(keys, key{a.key, a.help})
+		}
+	}
+	return keys
+}
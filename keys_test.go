@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// keepBindings restores the default bindings after the test.
+func keepBindings(t *testing.T) {
+	t.Helper()
+	keys := map[string]string{}
+	for _, a := range actions {
+		keys[a.id] = a.key
+	}
+	t.Cleanup(func() {
+		for _, a := range actions {
+			a.key = keys[a.id]
+		}
+	})
+}
+
+func TestDefaultBindings(t *testing.T) {
+	if err := checkBindings(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBindKey(t *testing.T) {
+	tests := []struct {
+		id, key string
+		want    string
+		wantErr bool
+	}{
+		{"copy", "y", "y", false},
+		{"copy", "ctrl-y", "Ctrl-Y", false},
+		{"copy", "f7", "F7", false},
+		{"quit-now", "F2", "F2", false},
+		{"about", "Ctrl-O", "Ctrl-O", false},
+		{"about", "x", "", true},
+		{"quit-now", "é", "", true},
+		{"copy", "Hyper-Y", "", true},
+		{"unknown", "y", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.id+" "+test.key, func(t *testing.T) {
+			keepBindings(t)
+			err := bindKey(test.id, test.key)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("bindKey(%s, %s) error = %v, want error %v", test.id, test.key, err, test.wantErr)
+			}
+			if !test.wantErr && boundKey(test.id) != test.want {
+				t.Errorf("bound key = %s, want %s", boundKey(test.id), test.want)
+			}
+		})
+	}
+}
+
+func TestCheckBindings(t *testing.T) {
+	tests := []struct {
+		name     string
+		bindings map[string]string
+		wantErr  string
+	}{
+		{"swapped keys", map[string]string{"copy": "m", "move": "c"}, ""},
+		{"other window", map[string]string{"file-print": "c"}, ""},
+		{"same window", map[string]string{"copy": "m"}, "bound to both"},
+		{"app key", map[string]string{"copy": "F1"}, "bound to both about and copy"},
+		{"app key in file window", map[string]string{"file-print": "Ctrl-Q"}, "bound to both quit-now and file-print"},
+		{"tree movement", map[string]string{"copy": "j"}, "used to move in the tree"},
+		{"tree page down", map[string]string{"help": "PgDn"}, "used to move in the tree"},
+		{"tree enter", map[string]string{"reload": "Enter"}, "used to move in the tree"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keepBindings(t)
+			for id, key := range test.bindings {
+				if err := bindKey(id, key); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := checkBindings()
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkBindings() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("checkBindings() error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadConfigBindings(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			"bindings", "# keys\nkey.copy = y\n\nkey.pane-copy F7  # function key\nkey.about=Ctrl-O\n",
+			map[string]string{"copy": "y", "pane-copy": "F7", "about": "Ctrl-O"}, "",
+		},
+		{"character for app action", "key.copy = y\nkey.quit-now = q\n", nil, ":2: quit-now needs a key"},
+		{"unknown key", "key.copy = Hyper-Y\n", nil, ":1: unknown key"},
+		{"unknown action", "key.nothing = y\n", nil, ":1: unknown action"},
+		{"unknown setting", "colour = red\n", nil, ":1: unknown setting"},
+		{"conflict", "key.copy = m\n", nil, "bound to both"},
+		{"tree movement", "key.copy = k\n", nil, "used to move in the tree"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keepBindings(t)
+			saved := configFile
+			t.Cleanup(func() { configFile = saved })
+			configFile = filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(configFile, []byte(test.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err := readConfig()
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("readConfig() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for id, key := range test.want {
+				if got := boundKey(id); got != key {
+					t.Errorf("key of %s = %s, want %s", id, got, key)
+				}
+			}
+		})
+	}
+}
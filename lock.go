@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// lockError explains a timeout opening file, naming the processes holding the lock where possible.
+func lockError(file string, err error) error {
+	holders := lockHolders(file)
+	if len(holders) == 0 {
+		return fmt.Errorf("%w (%w)", errLocked, err)
+	}
+	return fmt.Errorf("%w: %s", errLocked, strings.Join(holders, "; "))
+}
+
+// privateCopy copies file to the temp directory, without taking the lock, so it can be
+// inspected while another process uses it.
+func privateCopy(file string) (string, error) {
+	ext := filepath.Ext(file)
+	src, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	dst, err := os.CreateTemp("", strings.TrimSuffix(filepath.Base(file), ext)+"-copy-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), dst.Close()
+}
+
+// showLocked offers alternatives when file cannot be opened because another process holds the lock.
+// A writer holds an exclusive lock, so opening read-only only helps if the others are readers.
+func showLocked(file string, ro, newTab bool, err error) {
+	last := "Quit"
+	if newTab {
+		last = "Cancel"
+	}
+	dialog := tview.NewModal().
+		SetText(fmt.Sprintf("cannot open %s\n\n%v", file, err)).
+		AddButtons([]string{"Retry", "Open read only", "Open private copy", last}).
+		SetDoneFunc(func(_ int, label string) {
+			pager.RemovePage("locked")
+			switch label {
+			case "Retry":
+				openFile(file, ro, newTab)
+			case "Open read only":
+				openFile(file, true, newTab)
+			case "Open private copy":
+				copied, err := privateCopy(file)
+				if err != nil {
+					showError(err.Error())
+					return
+				}
+				openFile(copied, true, newTab)
+			case "Cancel":
+				app.SetFocus(tree)
+			default:
+				app.Stop()
+			}
+		})
+	dialog.SetTitle("Database Locked")
+	pager.AddPage("locked", dialog, true, true)
+	app.SetFocus(dialog)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// lockHolders describes the processes holding a lock on file, read from /proc/locks.
+// Lines look like "1: FLOCK  ADVISORY  WRITE 1234 08:02:131 0 EOF", where the device
+// numbers are hex and the inode is decimal.
+func lockHolders(file string) []string {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	dev := uint64(stat.Dev) //nolint:unconvert
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+	want := fmt.Sprintf("%02x:%02x:%d", major, minor, stat.Ino)
+	locks, err := os.ReadFile("/proc/locks")
+	if err != nil {
+		return nil
+	}
+	holders := []string{}
+	for _, line := range strings.Split(string(locks), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || fields[1] == "->" || fields[5] != want {
+			continue
+		}
+		pid := fields[4]
+		name := "unknown"
+		if comm, err := os.ReadFile("/proc/" + pid + "/comm"); err == nil {
+			name = strings.TrimSpace(string(comm))
+		}
+		holders = append(holders, fmt.Sprintf("pid %s (%s) holds a %s %s lock", pid, name, fields[3], strings.ToLower(fields[1])))
+	}
+	return holders
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// lockHolders is only implemented on linux.
+func lockHolders(string) []string {
+	return nil
+}
@@ -2,6 +2,10 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -17,35 +21,45 @@ var (
 	header  *tview.TextView
 	pager   *tview.Pages
 	tree    *tview.TreeView
+
+	openReadOnly bool
 )
 
 // Show a navigable tree view of the current directory.
 func main() { //nolint:funlen
+	flag.BoolVar(&openReadOnly, "readonly", false, "open the database read-only")
+	flag.BoolVar(&createFiles, "create", false, "create the database file if it does not exist")
+	flag.BoolVar(&shared, "shared", false, "open the database file only while reading or writing it")
+	flag.StringVar(&snapshotDir, "snapshots", "",
+		"take a snapshot into `dir` before a bucket is deleted, emptied or moved")
+	flag.BoolVar(&snapshotDB, "snapshot-db", false, "snapshot the whole database instead of the affected bucket")
+	flag.IntVar(&snapshotKeep, "snapshot-keep", 20, "number of snapshots to keep per database, 0 keeps all")
+	flag.DurationVar(&snapshotAge, "snapshot-age", 0, "remove snapshots older than `duration`, 0 keeps all")
+	optionFlags()
+	flag.Usage = usage
+	flag.Parse()
+	if err := readConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	args := flag.Args()
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			log.SetOutput(io.Discard)
+			os.Exit(runCommand(args[0], args[1:]))
+		}
+	}
 	InitLog()
 	header = textView("header")
 	dbfile := "test.db"
-	if len(os.Args) == 2 {
-		dbfile = os.Args[1]
+	if len(args) > 0 {
+		dbfile = args[0]
 	}
-	if err := InitDatabase(dbfile); err != nil {
-		panic(err)
+	initErr := InitDatabase(dbfile, openReadOnly)
+	if initErr != nil && !errors.Is(initErr, errLocked) && !errors.Is(initErr, errNoDatabase) {
+		panic(initErr)
 	}
-	details = tview.NewTextView()
-	details.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyEsc, tcell.KeyTAB:
-			app.SetFocus(tree)
-		case tcell.KeyRune:
-			switch event.Rune() { //nolint:gocritic
-			case '?':
-				f := tree.GetInputCapture()
-				f(event)
-			}
-			return nil
-		}
-		return event
-	})
-	details.SetBorder(true).SetTitle("Details").SetTitleAlign(tview.AlignCenter)
+	details = newDetails()
 	tree = newTree(details)
 
 	grid = mainGrid()
@@ -67,21 +81,40 @@ func main() { //nolint:funlen
 	app = tview.NewApplication().SetRoot(pager, true).EnableMouse(true)
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		log.Println("app key handler", event.Name())
-		switch event.Key() {
-		case tcell.KeyF1:
-			help := about(60, 22)
-			pager.AddPage("help", help, true, true)
-			app.SetFocus(help)
-			return nil
-		case tcell.KeyCtrlQ:
-			app.Stop()
-		case tcell.KeyCtrlC:
+		if bound := actionFor("app", event); bound != nil {
+			switch bound.id {
+			case "about":
+				help := about(60, 22)
+				pager.AddPage("help", help, true, true)
+				app.SetFocus(help)
+				return nil
+			case "quit-now":
+				app.Stop()
+			}
+		}
+		if event.Key() == tcell.KeyCtrlC {
 			return tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModNone)
 		}
 		log.Println("app key handling: passing ", event.Name())
 		return event
 	})
 
+	// further files are opened in tabs
+	for _, file := range args[1:] {
+		if err := openTab(file, openReadOnly); err != nil {
+			showError(err.Error())
+			break
+		}
+	}
+	selectTab(0)
+	switch {
+	case errors.Is(initErr, errLocked):
+		showLocked(dbfile, openReadOnly, false, initErr)
+	case errors.Is(initErr, errNoDatabase):
+		create := modal(newDatabaseForm(dbfile, true, "new"), 60, 11)
+		pager.AddPage("new", create, true, true)
+		app.SetFocus(create)
+	}
 	if err := app.Run(); err != nil {
 		panic(err)
 	}
@@ -97,20 +130,89 @@ func InitLog() {
 	log.Default().SetOutput(logFile)
 }
 
+// newDetails creates the details pane of a tree.
+func newDetails() *tview.TextView {
+	detail := tview.NewTextView()
+	detail.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc, tcell.KeyTAB:
+			app.SetFocus(tree)
+		case tcell.KeyRune:
+			// help and display mode also work in the details pane
+			if bound := actionFor("tree", event); bound != nil && (bound.id == "help" || bound.id == "display-mode") {
+				f := tree.GetInputCapture()
+				f(event)
+			}
+			return nil
+		}
+		return event
+	})
+	detail.SetBorder(true).SetTitle("Details (" + display.String() + ")").SetTitleAlign(tview.AlignCenter)
+	return detail
+}
+
 func textView(text string) *tview.TextView {
 	return tview.NewTextView().
 		SetTextAlign(tview.AlignCenter).
 		SetText(text)
 }
 
+// headerRows is the height of the header, which lists the tabs if more than one database is open.
+func headerRows() int {
+	if len(tabs) > 1 {
+		return 2
+	}
+	return 1
+}
+
+// footer is the hint at the bottom of the main window, or the command line while it is open.
+func footer(hint string) tview.Primitive { //nolint:ireturn
+	if commandLine != nil {
+		return commandLine
+	}
+	return textView(hint)
+}
+
 func mainGrid() *tview.Grid {
+	pendingView = nil
+	if dualPane {
+		grid = tview.NewGrid().
+			SetRows(headerRows(), 0, 8, 1).
+			SetColumns(0, 0).
+			SetBorders(true).
+			AddItem(header, 0, 0, 1, 2, 0, 0, false).
+			AddItem(footer(fmt.Sprintf("two-pane mode: %s switches pane, %s copies and %s moves to the other pane, %s leaves",
+				boundKey("switch-pane"), boundKey("pane-copy"), boundKey("pane-move"), boundKey("two-pane"))),
+				3, 0, 1, 2, 0, 0, commandLine != nil)
+		for i, p := range panes {
+			grid.AddItem(p.tree, 1, i, 1, 1, 0, 0, commandLine == nil && i == activePane).
+				AddItem(p.details, 2, i, 1, 1, 0, 0, false)
+		}
+		return grid
+	}
+	if staging {
+		pendingView = tview.NewTextView()
+		pendingView.SetBorder(true)
+		showPending()
+		grid = tview.NewGrid().
+			SetRows(headerRows(), 0, 1).
+			SetColumns(0, 0, 30).
+			SetBorders(true).
+			AddItem(header, 0, 0, 1, 3, 0, 0, false).
+			AddItem(footer("transaction mode: press "+boundKey("transaction")+" to commit or discard the pending changes"), 2, 0, 1, 3, 0, 0, commandLine != nil).
+			AddItem(tree, 1, 0, 1, 1, 0, 0, commandLine == nil).
+			AddItem(details, 1, 1, 1, 1, 0, 0, false).
+			AddItem(pendingView, 1, 2, 1, 1, 0, 0, false)
+		return grid
+	}
 	grid = tview.NewGrid().
-		SetRows(1, 0, 1).
+		SetRows(headerRows(), 0, 1).
 		SetColumns(0, 0).
 		SetBorders(true).
 		AddItem(header, 0, 0, 1, 2, 0, 0, false).
-		AddItem(textView("press ? or F1 for help, esc or ctrl-Q to quit"), 2, 0, 1, 2, 0, 0, false).
-		AddItem(tree, 1, 0, 1, 1, 0, 0, true).
+		AddItem(footer(fmt.Sprintf("press %s or %s for help, %s for commands, %s or %s to quit",
+			boundKey("help"), boundKey("about"), boundKey("command-line"), boundKey("quit"), boundKey("quit-now"))), 2, 0, 1, 2, 0, 0, commandLine != nil).
+		AddItem(tree, 1, 0, 1, 1, 0, 0, commandLine == nil).
 		AddItem(details, 1, 1, 1, 1, 0, 0, false)
 	grid.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		log.Println("grid key handler", event.Key())
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+var freelistTypes = []bbolt.FreelistType{bbolt.FreelistArrayType, bbolt.FreelistMapType}
+
+// createDatabase creates a new database file with the given page size, which is stored
+// in the file. The file is opened afterwards with the configured options like any other.
+func createDatabase(file string, pageSize int) error {
+	if _, err := os.Stat(file); err == nil {
+		return fmt.Errorf("%s already exists", file)
+	}
+	options := openOptions(false)
+	options.PageSize = pageSize
+	created, err := bbolt.Open(file, 0o666, options)
+	if err != nil {
+		return err
+	}
+	return created.Close()
+}
+
+// newDatabaseForm creates a database file and opens it. A given file is one that was not
+// found; at startup there is nothing else to show, so cancel quits.
+func newDatabaseForm(file string, startup bool, dialog string) *tview.Form {
+	pageSize := dbOptions.PageSize
+	if pageSize == 0 {
+		pageSize = os.Getpagesize()
+	}
+	form := tview.NewForm()
+	if file != "" {
+		form.AddTextView("", file+" does not exist", 0, 1, true, false)
+	}
+	form.AddInputField("file:", file, 0, nil, nil).
+		AddInputField("page size:", strconv.Itoa(pageSize), 8, tview.InputFieldInteger, nil)
+	cancel := "Cancel"
+	if startup {
+		cancel = "Quit"
+	}
+	form.AddButton(cancel, func() {
+		if startup {
+			app.Stop()
+			return
+		}
+		pager.RemovePage(dialog)
+		app.SetFocus(tree)
+	})
+	form.AddButton("Create", func() {
+		first := form.GetFormItemIndex("file:")
+		file := form.GetFormItem(first).(*tview.InputField).GetText()
+		pageSize, err := parsePageSize(form.GetFormItem(first + 1).(*tview.InputField).GetText())
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+		if err := createDatabase(file, pageSize); err != nil {
+			showError(err.Error())
+			return
+		}
+		pager.RemovePage(dialog)
+		// at startup the empty main window is replaced
+		openFile(file, false, !startup)
+	})
+	form.SetButtonsAlign(tview.AlignCenter).
+		SetBorder(true).SetTitle("New Database").SetTitleAlign(tview.AlignCenter)
+	return form
+}
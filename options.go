@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var configFile string
+
+// freelistValue is a flag for the freelist type of dbOptions.
+type freelistValue bbolt.FreelistType
+
+func (f *freelistValue) String() string {
+	return string(*f)
+}
+
+func (f *freelistValue) Set(value string) error {
+	for _, freelist := range freelistTypes {
+		if value == string(freelist) {
+			*f = freelistValue(freelist)
+			return nil
+		}
+	}
+	return errors.New("must be array or hashmap")
+}
+
+// optionFlags adds the flags for the bbolt options used to open database files.
+func optionFlags() {
+	dbOptions.Timeout = time.Second
+	dbOptions.FreelistType = bbolt.FreelistArrayType
+	flag.DurationVar(&dbOptions.Timeout, "timeout", dbOptions.Timeout, "wait up to `duration` for the file lock")
+	flag.BoolVar(&dbOptions.NoSync, "nosync", false, "do not fsync after each commit")
+	flag.BoolVar(&dbOptions.NoFreelistSync, "nofreelistsync", false, "do not write the freelist to disk")
+	flag.Var((*freelistValue)(&dbOptions.FreelistType), "freelist", "freelist `type`: array or hashmap")
+	flag.IntVar(&dbOptions.PageSize, "pagesize", 0, "page `size` of new database files, 0 uses the OS page size")
+	flag.IntVar(&dbOptions.InitialMmapSize, "mmapsize", 0, "initial mmap `size` in bytes")
+	flag.BoolVar(&dbOptions.Mlock, "mlock", false, "lock the database file in memory")
+	flag.BoolVar(&dbOptions.NoGrowSync, "nogrowsync", false, "do not fsync when the file grows")
+	flag.StringVar(&configFile, "config", defaultConfigFile(), "read settings from `file`")
+}
+
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "bboltEdit", "config")
+}
+
+// readConfig applies the settings of the config file. Each line is a flag name, or key. and
+// an action id, an optional = and the value; # starts a comment. Flags given on the command
+// line take precedence.
+func readConfig() error {
+	if configFile == "" {
+		return nil
+	}
+	file, err := os.Open(configFile)
+	if err != nil {
+		explicit := false
+		flag.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "config" })
+		if errors.Is(err, fs.ErrNotExist) && !explicit {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(text, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if value == "" {
+			name, value, _ = strings.Cut(name, " ")
+			value = strings.TrimSpace(value)
+		}
+		if id, ok := strings.CutPrefix(name, "key."); ok {
+			if err := bindKey(id, value); err != nil {
+				return fmt.Errorf("%s:%d: %w", configFile, line, err)
+			}
+			continue
+		}
+		setting := flag.Lookup(name)
+		if setting == nil || name == "config" {
+			return fmt.Errorf("%s:%d: unknown setting %s", configFile, line, name)
+		}
+		if boolFlag, ok := setting.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() && value == "" {
+			value = "true"
+		}
+		if given[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", configFile, line, name, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return checkBindings()
+}
+
+// optionsSummary lists the options used to open database files for the header.
+func optionsSummary() string {
+	options := []string{"timeout " + dbOptions.Timeout.String(), "freelist " + string(dbOptions.FreelistType)}
+	if dbOptions.PageSize > 0 {
+		options = append(options, "page size "+strconv.Itoa(dbOptions.PageSize))
+	}
+	if dbOptions.InitialMmapSize > 0 {
+		options = append(options, "mmap size "+strconv.Itoa(dbOptions.InitialMmapSize))
+	}
+	for _, option := range []struct {
+		name string
+		set  bool
+	}{
+		{"NoSync", dbOptions.NoSync},
+		{"NoFreelistSync", dbOptions.NoFreelistSync},
+		{"NoGrowSync", dbOptions.NoGrowSync},
+		{"Mlock", dbOptions.Mlock},
+	} {
+		if option.set {
+			options = append(options, option.name)
+		}
+	}
+	return strings.Join(options, ", ")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// paletteEntry is a tree action or a command of the command line in the command palette.
+type paletteEntry struct {
+	key  string
+	help string
+	// match is the text the typed pattern is matched against
+	match string
+	run   func()
+}
+
+func paletteEntries() []paletteEntry {
+	entries := []paletteEntry{}
+	for _, a := range actions {
+		if a.window != "tree" || a.id == "command-palette" {
+			continue
+		}
+		entries = append(entries, paletteEntry{
+			key: a.key, help: a.help, match: a.help + " " + a.id,
+			run: func() { runAction(a.id) },
+		})
+	}
+	for _, cmd := range lineCommands {
+		entries = append(entries, paletteEntry{
+			key: ":" + cmd.name, help: cmd.help, match: cmd.help + " " + cmd.usage,
+			run: func() { showCommandLine(cmd.name + " ") },
+		})
+	}
+	return entries
+}
+
+// fuzzyScore reports whether the characters of pattern appear in text in order, ignoring
+// case. Consecutive characters and characters at the start of a word score higher.
+func fuzzyScore(pattern, text string) (int, bool) {
+	runes := []rune(strings.ToLower(text))
+	score, last, i := 0, -1, 0
+	for _, p := range strings.ToLower(pattern) {
+		for i < len(runes) && runes[i] != p {
+			i++
+		}
+		if i == len(runes) {
+			return 0, false
+		}
+		switch {
+		case i == last+1:
+			score += 3
+		case !unicode.IsLetter(runes[i-1]):
+			score += 2
+		default:
+			score++
+		}
+		last = i
+		i++
+	}
+	return score, true
+}
+
+// commandPalette lists the actions and commands matching the typed text, best matches
+// first. Enter runs the selected one; commands open the command line to enter their arguments.
+func commandPalette(dialog string) *tview.Flex {
+	entries := paletteEntries()
+	input := tview.NewInputField().SetLabel("> ")
+	list := tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	shown := []paletteEntry{}
+	run := func(index int) {
+		if index < 0 || index >= len(shown) {
+			return
+		}
+		pager.RemovePage(dialog)
+		app.SetFocus(tree)
+		shown[index].run()
+	}
+	filter := func(text string) {
+		type match struct {
+			entry paletteEntry
+			score int
+		}
+		matches := []match{}
+		for _, entry := range entries {
+			if score, ok := fuzzyScore(text, entry.match); ok {
+				matches = append(matches, match{entry, score})
+			}
+		}
+		slices.SortStableFunc(matches, func(a, b match) int { return b.score - a.score })
+		list.Clear()
+		shown = shown[:0]
+		for _, m := range matches {
+			list.AddItem(tview.Escape(fmt.Sprintf("%-10s %s", m.entry.key, m.entry.help)), "", 0, nil)
+			shown = append(shown, m.entry)
+		}
+	}
+	filter("")
+	input.SetChangedFunc(filter)
+	list.SetSelectedFunc(func(index int, _, _ string, _ rune) {
+		run(index)
+	})
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn:
+			list.InputHandler()(event, func(tview.Primitive) {})
+			return nil
+		case tcell.KeyEnter:
+			run(list.GetCurrentItem())
+			return nil
+		}
+		return event
+	})
+	palette := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(input, 1, 0, true).
+		AddItem(list, 0, 1, false)
+	palette.SetBorder(true).SetTitle("Commands").SetTitleAlign(tview.AlignCenter)
+	return palette
+}
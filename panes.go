@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// pane is one side of the two-pane mode: a tree of the database of a tab. While a pane
+// is active, its tab is the active tab and its tree and details are in the globals.
+type pane struct {
+	tab     int
+	tree    *tview.TreeView
+	details *tview.TextView
+}
+
+var (
+	dualPane   bool
+	panes      [2]pane
+	activePane int
+	// highlighted are the tree nodes colored by markDifferences
+	highlighted []*tview.TreeNode
+)
+
+// startDualPane shows the active tab next to the following one, or a second tree of the
+// same database if only one is open.
+func startDualPane() {
+	saveTab()
+	other := (activeTab + 1) % len(tabs)
+	panes[0] = pane{tab: activeTab, tree: tree, details: details}
+	if other == activeTab {
+		detail := newDetails()
+		panes[1] = pane{tab: activeTab, tree: newTree(detail), details: detail}
+	} else {
+		panes[1] = pane{tab: other, tree: tabs[other].tree, details: tabs[other].details}
+	}
+	for _, p := range panes {
+		p.tree.SetTitle(filepath.Base(tabFile(p.tab)))
+	}
+	dualPane = true
+	activePane = 0
+	showMain()
+	markDifferences()
+}
+
+// leaveDualPane goes back to a single tree, the one of the active pane.
+func leaveDualPane() {
+	if !dualPane {
+		return
+	}
+	dualPane = false
+	markDifferences()
+	// treeTitle depends on the tab, so restore the titles with each pane active
+	active := activePane
+	activatePane(1 - active)
+	tree.SetTitle(treeTitle())
+	activatePane(active)
+	tree.SetTitle(treeTitle())
+	panes = [2]pane{}
+}
+
+// activatePane makes the tab and tree of pane index active.
+func activatePane(index int) {
+	saveTab()
+	loadTab(panes[index].tab)
+	tree, details = panes[index].tree, panes[index].details
+	activePane = index
+	setHeader()
+	app.SetFocus(tree)
+}
+
+// paneLocation returns the bucket node around the selection of a pane, which is the
+// selected bucket if it is expanded and the parent of the selection otherwise, and its path.
+func paneLocation(t *tview.TreeView) (*tview.TreeNode, []string) {
+	current := t.GetCurrentNode()
+	reference, ok := current.GetReference().(dbNode)
+	if !ok {
+		return current, nil
+	}
+	if reference.kind == "bucket" && current.IsExpanded() {
+		return current, reference.path
+	}
+	nodes := t.GetPath(current)
+	if len(nodes) < 2 {
+		return t.GetRoot(), nil
+	}
+	parent := nodes[len(nodes)-2]
+	if reference, ok := parent.GetReference().(dbNode); ok {
+		return parent, reference.path
+	}
+	return parent, nil
+}
+
+// paneEntries looks up the keys and buckets called names in the bucket at path in the
+// database of a pane. Names that do not exist there are left out.
+func paneEntries(p pane, path []string, names [][]byte) (map[string]*entry, error) {
+	entries := map[string]*entry{}
+	read := func() error {
+		return view(func(tx *bbolt.Tx) error {
+			var bucket *bbolt.Bucket
+			if len(path) > 0 {
+				var err error
+				if bucket, err = getBucket(path, tx); err != nil {
+					return err
+				}
+			}
+			for _, name := range names {
+				switch {
+				case bucket == nil:
+					if tx.Bucket(name) != nil {
+						entries[string(name)] = &entry{name: name, bucket: true}
+					}
+				case bucket.Bucket(name) != nil:
+					entries[string(name)] = &entry{name: name, bucket: true}
+				default:
+					if value := bucket.Get(name); value != nil {
+						entries[string(name)] = &entry{name: name, value: slices.Clone(value)}
+					}
+				}
+			}
+			return nil
+		})
+	}
+	if p.tab == activeTab {
+		return entries, read()
+	}
+	return entries, inTab(p.tab, read)
+}
+
+// markDifferences colors the entries around the selection of each pane that are missing
+// (red) or different (yellow) at the location of the other pane. Buckets are compared by
+// name. Only the entries loaded in the trees are looked up.
+func markDifferences() {
+	for _, node := range highlighted {
+		if reference, ok := node.GetReference().(dbNode); ok {
+			node.SetColor(nodeColor(reference))
+		}
+	}
+	highlighted = nil
+	if !dualPane {
+		return
+	}
+	nodes := [2]*tview.TreeNode{}
+	paths := [2][]string{}
+	names := [][]byte{}
+	for i, p := range panes {
+		nodes[i], paths[i] = paneLocation(p.tree)
+		for _, child := range nodes[i].GetChildren() {
+			if reference, ok := child.GetReference().(dbNode); ok && reference.kind != "more" {
+				names = append(names, reference.name)
+			}
+		}
+	}
+	entries := [2]map[string]*entry{}
+	for i, p := range panes {
+		var err error
+		if entries[i], err = paneEntries(p, paths[i], names); err != nil {
+			return
+		}
+	}
+	for i, node := range nodes {
+		for _, child := range node.GetChildren() {
+			reference, ok := child.GetReference().(dbNode)
+			if !ok || reference.kind == "more" {
+				continue
+			}
+			own, other := entries[i][string(reference.name)], entries[1-i][string(reference.name)]
+			switch {
+			case own == nil:
+			case other == nil:
+				child.SetColor(tcell.ColorRed)
+			case own.bucket != other.bucket || !bytes.Equal(own.value, other.value):
+				child.SetColor(tcell.ColorYellow)
+			default:
+				continue
+			}
+			highlighted = append(highlighted, child)
+		}
+	}
+}
+
+// transfer copies or moves the selected key or bucket to the other pane and reports errors.
+func transfer(move bool) {
+	if err := transferToPane(move); err != nil {
+		showRetry(err, func() { transfer(move) })
+	}
+}
+
+// transferToPane copies or moves the selected key or bucket to the location of the other pane.
+func transferToPane(move bool) error {
+	node := getCurrentNode()
+	if len(node.path) == 0 {
+		return errors.New("select a key or bucket")
+	}
+	other := panes[1-activePane]
+	_, location := paneLocation(other.tree)
+	newpath := append(slices.Clone(location), string(node.name))
+	if move && readOnly {
+		return errors.New("database is open read only")
+	}
+	if other.tab != activeTab {
+		transfer := copyToTab
+		if move {
+			transfer = moveToTab
+		}
+		if err := transfer(node, other.tab, newpath); err != nil {
+			return err
+		}
+		if move {
+			reloadParent(node.path)
+		}
+		markDifferences()
+		return nil
+	}
+	if slices.Equal(newpath, node.path) {
+		return errors.New("source and destination are the same")
+	}
+	if node.kind == "bucket" && withinPath(newpath, node.path) {
+		if move {
+			return errors.New("cannot move a bucket into itself")
+		}
+		return errors.New("cannot copy a bucket into itself")
+	}
+	if readOnly {
+		return errors.New("database is open read only")
+	}
+	var err error
+	if move {
+		err = record("move", [][]string{node.path, newpath}, func() error {
+			return moveItem(node, newpath)
+		})
+	} else {
+		err = record("copy", [][]string{newpath}, func() error {
+			return copyItem(node, newpath)
+		})
+	}
+	if err != nil {
+		return err
+	}
+	// both panes show the same database
+	current := tree
+	tree = other.tree
+	reloadAndSetSelection(newpath)
+	tree = current
+	if move {
+		reloadParent(node.path)
+	} else {
+		reloadAndSetSelection(node.path)
+	}
+	markDifferences()
+	return nil
+}
+
+// reloadParent reloads the tree and selects and expands the bucket that held path.
+func reloadParent(path []string) {
+	if len(path) < 2 {
+		reloadTree()
+		return
+	}
+	reloadAndSetSelection(path[:len(path)-1])
+	parent := tree.GetCurrentNode()
+	loadChildren(parent)
+	parent.Expand()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// parsePath splits the text of a path field into bucket and key names.
+// Names are separated by spaces. A name containing spaces or special characters is
+// written in double quotes using go string escapes ("a b", "\x00\x01", "tab\t").
+// Outside of quotes a backslash escapes the next character and \xNN is a hex escaped byte.
+func parsePath(text string) ([]string, error) {
+	return splitPath(text, ' ')
+}
+
+// splitPath is parsePath with names separated by sep instead of spaces.
+func splitPath(text string, sep byte) ([]string, error) {
+	path := []string{}
+	var name strings.Builder
+	started := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == sep:
+			if started {
+				path = append(path, name.String())
+				name.Reset()
+				started = false
+			}
+		case c == '"':
+			end := i + 1
+			for ; end < len(text) && text[end] != '"'; end++ {
+				if text[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(text) {
+				return nil, errors.New("invalid path: missing closing quote")
+			}
+			unquoted, err := strconv.Unquote(text[i : end+1])
+			if err != nil {
+				return nil, errors.New("invalid path: bad escape in " + text[i:end+1])
+			}
+			name.WriteString(unquoted)
+			started = true
+			i = end
+		case c == '\\':
+			if i+1 >= len(text) {
+				return nil, errors.New("invalid path: trailing backslash")
+			}
+			i++
+			if text[i] == 'x' {
+				if i+2 >= len(text) {
+					return nil, errors.New("invalid path: short hex escape")
+				}
+				b, err := strconv.ParseUint(text[i+1:i+3], 16, 8)
+				if err != nil {
+					return nil, errors.New("invalid path: bad hex escape \\x" + text[i+1:i+3])
+				}
+				name.WriteByte(byte(b))
+				i += 2
+			} else {
+				name.WriteByte(text[i])
+			}
+			started = true
+		default:
+			name.WriteByte(c)
+			started = true
+		}
+	}
+	if started {
+		path = append(path, name.String())
+	}
+	return path, nil
+}
+
+// parseName parses the text of a name field, which must hold exactly one name.
+func parseName(text string) (string, error) {
+	path, err := parsePath(text)
+	if err != nil {
+		return "", err
+	}
+	if len(path) != 1 {
+		return "", errors.New("invalid name: quote names that contain spaces")
+	}
+	return path[0], nil
+}
+
+// formatPath is the inverse of parsePath. Names that are not plain printable
+// text are quoted.
+func formatPath(path []string) string {
+	names := make([]string, 0, len(path))
+	for _, name := range path {
+		names = append(names, formatPathName(name))
+	}
+	return strings.Join(names, " ")
+}
+
+func formatPathName(name string) string {
+	if name == "" || !utf8.ValidString(name) || strings.ContainsFunc(name, func(r rune) bool {
+		return r == '"' || r == '\\' || r == ' ' || !unicode.IsPrint(r)
+	}) {
+		return strconv.Quote(name)
+	}
+	return name
+}
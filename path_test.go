@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{"", []string{}, false},
+		{"a", []string{"a"}, false},
+		{"a b  c ", []string{"a", "b", "c"}, false},
+		{`"a b" c`, []string{"a b", "c"}, false},
+		{`"say \"hi\""`, []string{`say "hi"`}, false},
+		{`a\ b`, []string{"a b"}, false},
+		{`\"quoted`, []string{`"quoted`}, false},
+		{`\x00\xff`, []string{"\x00\xff"}, false},
+		{`"\x00\x01" "tab\t"`, []string{"\x00\x01", "tab\t"}, false},
+		{`pre"fix"`, []string{"prefix"}, false},
+		{`""`, []string{""}, false},
+		{"é 日本", []string{"é", "日本"}, false},
+		{`"open`, nil, true},
+		{`"bad \q"`, nil, true},
+		{`a\`, nil, true},
+		{`\x0`, nil, true},
+		{`\xzz`, nil, true},
+	}
+	for _, test := range tests {
+		got, err := parsePath(test.text)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parsePath(%q) error = %v, want error %v", test.text, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parsePath(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestFormatPathRoundTrip(t *testing.T) {
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{[]string{}, ""},
+		{[]string{"a", "b"}, "a b"},
+		{[]string{"a b"}, `"a b"`},
+		{[]string{`say "hi"`}, `"say \"hi\""`},
+		{[]string{`back\slash`}, `"back\\slash"`},
+		{[]string{"\x00\x01"}, `"\x00\x01"`},
+		{[]string{"\xff\xfe"}, `"\xff\xfe"`},
+		{[]string{"tab\t", "new\nline"}, `"tab\t" "new\nline"`},
+		{[]string{""}, `""`},
+		{[]string{"é", "日本"}, "é 日本"},
+		{[]string{" "}, `" "`},
+		{[]string{"\u2028"}, `"\u2028"`},
+	}
+	for _, test := range tests {
+		got := formatPath(test.path)
+		if got != test.want {
+			t.Errorf("formatPath(%q) = %s, want %s", test.path, got, test.want)
+		}
+		parsed, err := parsePath(got)
+		if err != nil {
+			t.Errorf("parsePath(%s) error = %v", got, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, test.path) {
+			t.Errorf("parsePath(formatPath(%q)) = %q", test.path, parsed)
+		}
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"a", "a", false},
+		{`"a b"`, "a b", false},
+		{`\x00`, "\x00", false},
+		{"a b", "", true},
+		{"", "", true},
+	}
+	for _, test := range tests {
+		got, err := parseName(test.text)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("parseName(%q) = %q, %v, want %q, error %v", test.text, got, err, test.want, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// maxHits limits the number of search results.
+const maxHits = 10000
+
+var errTooManyHits = errors.New("too many matches")
+
+type searchHit struct {
+	path    []string
+	kind    string
+	inName  bool
+	inValue bool
+}
+
+var (
+	searchPattern string
+	searchHits    []searchHit
+	searchIndex   int
+)
+
+// findEntries returns all keys and buckets whose name, or keys whose value, matches the pattern.
+// The pattern is a regular expression if regex is set and a substring otherwise.
+func findEntries(pattern string, regex, names, values bool) ([]searchHit, error) {
+	match := func(data []byte) bool { return bytes.Contains(data, []byte(pattern)) }
+	if regex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		match = re.Match
+	}
+	hits := []searchHit{}
+	var walk func(path []string, bucket *bbolt.Bucket) error
+	walk = func(path []string, bucket *bbolt.Bucket) error {
+		return bucket.ForEach(func(k, v []byte) error {
+			hit := searchHit{path: append(slices.Clone(path), string(k)), kind: "key"}
+			if v == nil {
+				hit.kind = "bucket"
+			}
+			hit.inName = names && match(k)
+			hit.inValue = values && v != nil && match(v)
+			if hit.inName || hit.inValue {
+				if len(hits) == maxHits {
+					return errTooManyHits
+				}
+				hits = append(hits, hit)
+			}
+			if v == nil {
+				return walk(hit.path, bucket.Bucket(k))
+			}
+			return nil
+		})
+	}
+	err := view(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+			path := []string{string(name)}
+			if names && match(name) {
+				if len(hits) == maxHits {
+					return errTooManyHits
+				}
+				hits = append(hits, searchHit{path: path, kind: "bucket", inName: true})
+			}
+			return walk(path, b)
+		})
+	})
+	if errors.Is(err, errTooManyHits) {
+		err = nil
+	}
+	return hits, err
+}
+
+// gotoHit selects the search result at index in the tree.
+func gotoHit(index int) {
+	if len(searchHits) == 0 {
+		showError("no search results")
+		return
+	}
+	searchIndex = (index + len(searchHits)) % len(searchHits)
+	hit := searchHits[searchIndex]
+	selectNode(hit.path)
+	tree.SetTitle(fmt.Sprintf("%s (match %d of %d)", treeTitle(), searchIndex+1, len(searchHits)))
+}
+
+// searchResults lists the search hits. Selecting one jumps to it in the tree.
+func searchResults(dialog string) *tview.List {
+	list := tview.NewList()
+	for _, hit := range searchHits {
+		where := "name"
+		switch {
+		case hit.inName && hit.inValue:
+			where = "name and value"
+		case hit.inValue:
+			where = "value"
+		}
+		list.AddItem(tview.Escape(displayPath(hit.path)), hit.kind+" "+where+" matches", 0, nil)
+	}
+	list.SetSelectedFunc(func(index int, _, _ string, _ rune) {
+		pager.RemovePage(dialog)
+		gotoHit(index)
+		app.SetFocus(tree)
+	})
+	title := fmt.Sprintf("%d matches for %q", len(searchHits), searchPattern)
+	if len(searchHits) == maxHits {
+		title = fmt.Sprintf("first %d matches for %q", maxHits, searchPattern)
+	}
+	list.SetBorder(true).SetTitle(title).SetTitleAlign(tview.AlignCenter)
+	return list
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// In shared mode the database file is only opened, and locked, while an operation
+// reads or writes it, so other processes can use it in between.
+var (
+	shared bool
+	// loadedTxID is the id of the last transaction seen when the tree was loaded or changed
+	loadedTxID int
+	// stale is set when another process changed the file since the tree was loaded
+	stale bool
+)
+
+const sharedAttempts = 5
+
+var (
+	errLocked        = errors.New("database file is locked by another process")
+	errChangedOnDisk = errors.New("database was changed by another process, reload with ctrl-R first")
+)
+
+// openShared opens the database file for one operation. While another process holds
+// the lock it retries with increasing delays.
+func openShared(writable bool) (*bbolt.DB, error) {
+	delay := 50 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		options := openOptions(readOnly || !writable)
+		options.Timeout = 100 * time.Millisecond
+		opened, err := bbolt.Open(old, 0o666, options)
+		if !errors.Is(err, bbolt.ErrTimeout) {
+			return opened, err
+		}
+		if attempt == sharedAttempts {
+			return nil, lockError(old, err)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// acquireDB returns the database for an operation and a function to call when it is done.
+func acquireDB(writable bool) (*bbolt.DB, func(), error) {
+	if !shared {
+		if db == nil {
+			return nil, nil, errors.New("database not open")
+		}
+		return db, func() {}, nil
+	}
+	opened, err := openShared(writable)
+	if err != nil {
+		return nil, nil, err
+	}
+	return opened, func() { opened.Close() }, nil
+}
+
+// useDB runs fn with the database, opening the file for fn only in shared mode.
+func useDB(writable bool, fn func(d *bbolt.DB) error) error {
+	d, release, err := acquireDB(writable)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(d)
+}
+
+// noteTxID marks the tree stale if the file was changed by another process.
+func noteTxID(id int) {
+	if shared && !stale && id != loadedTxID {
+		stale = true
+		setHeader()
+	}
+}
+
+// checkTxID refuses to write if the file was changed by another process, because
+// the change would be based on what the tree showed before.
+func checkTxID(id int) error {
+	if shared && id != loadedTxID {
+		return errChangedOnDisk
+	}
+	return nil
+}
+
+// showRetry offers to retry an operation that failed because the file is locked. Other
+// errors are shown as usual.
+func showRetry(err error, retry func()) {
+	if !errors.Is(err, errLocked) {
+		showError(tview.Escape(err.Error()))
+		return
+	}
+	dialog := tview.NewModal().
+		SetText(tview.Escape(err.Error())).
+		AddButtons([]string{"Retry", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			// the dialog of the operation, if any, gets the focus back
+			pager.RemovePage("retry")
+			if front, _ := pager.GetFrontPage(); front == "main" {
+				app.SetFocus(tree)
+			}
+			if label == "Retry" {
+				retry()
+			}
+		})
+	pager.AddPage("retry", dialog, true, true)
+	app.SetFocus(dialog)
+}
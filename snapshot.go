@@ -0,0 +1,285 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// snapshots are taken before buckets are deleted, emptied or moved if snapshotDir is set.
+// They hold the affected bucket as json export, or with snapshotDB a backup of the whole database.
+var (
+	snapshotDir  string
+	snapshotDB   bool
+	snapshotKeep int
+	snapshotAge  time.Duration
+)
+
+const snapshotTimeFormat = "20060102-150405"
+
+// snapshot is a file in snapshotDir named <prefix><time>.<operation>[-n].json or .db.
+type snapshot struct {
+	file      string
+	time      time.Time
+	operation string
+}
+
+// takeSnapshot saves the bucket at path before the operation changes it and removes
+// snapshots that are no longer kept.
+func takeSnapshot(operation string, path []string) error {
+	if snapshotDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(snapshotDir, 0o755); err != nil {
+		return fmt.Errorf("snapshot before %s: %w", operation, err)
+	}
+	ext := ".json"
+	if snapshotDB {
+		ext = ".db"
+	}
+	base := filepath.Join(snapshotDir, snapshotPrefix()+time.Now().Format(snapshotTimeFormat)+"."+operation)
+	file := base + ext
+	for i := 2; fileSize(file) > 0; i++ {
+		file = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	var err error
+	if snapshotDB {
+		err = backupDatabase(file)
+	} else {
+		err = exportFile(file, path)
+	}
+	if err != nil {
+		os.Remove(file)
+		return fmt.Errorf("snapshot before %s: %w", operation, err)
+	}
+	return pruneSnapshots()
+}
+
+// snapshotPrefix starts the snapshot names of the open database: the file name and a hash
+// of its absolute path, so databases with the same name in different directories do not
+// share their snapshots.
+func snapshotPrefix() string {
+	abs, err := filepath.Abs(old)
+	if err != nil {
+		abs = old
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Base(old) + "-" + hex.EncodeToString(sum[:4]) + "."
+}
+
+// listSnapshots returns the snapshots of the open database, newest first.
+func listSnapshots() ([]snapshot, error) {
+	if snapshotDir == "" {
+		return nil, nil
+	}
+	files, err := os.ReadDir(snapshotDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	prefix := snapshotPrefix()
+	snapshots := []snapshot{}
+	for _, file := range files {
+		name := file.Name()
+		ext := filepath.Ext(name)
+		if file.IsDir() || !strings.HasPrefix(name, prefix) || (ext != ".json" && ext != ".db") {
+			continue
+		}
+		stamp, operation, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext), ".")
+		if !ok {
+			continue
+		}
+		taken, err := time.ParseInLocation(snapshotTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		operation, _, _ = strings.Cut(operation, "-")
+		snapshots = append(snapshots, snapshot{
+			file: filepath.Join(snapshotDir, name), time: taken, operation: operation,
+		})
+	}
+	slices.SortStableFunc(snapshots, func(a, b snapshot) int {
+		if c := b.time.Compare(a.time); c != 0 {
+			return c
+		}
+		return strings.Compare(b.file, a.file)
+	})
+	return snapshots, nil
+}
+
+// pruneSnapshots removes snapshots beyond the newest snapshotKeep and those older than
+// snapshotAge. A zero setting keeps all.
+func pruneSnapshots() error {
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return err
+	}
+	for i, s := range snapshots {
+		if (snapshotKeep > 0 && i >= snapshotKeep) || (snapshotAge > 0 && time.Since(s.time) > snapshotAge) {
+			if err := os.Remove(s.file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readSnapshot returns the entries of a snapshot and the bucket they were taken from.
+func readSnapshot(file string) ([]string, []*entry, error) {
+	if filepath.Ext(file) == ".db" {
+		other, err := bbolt.Open(file, 0o666, openOptions(true))
+		if err != nil {
+			return nil, nil, err
+		}
+		defer other.Close()
+		entries := []*entry{}
+		err = other.View(func(tx *bbolt.Tx) error {
+			return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+				e, err := copyEntry(name, b)
+				entries = append(entries, e)
+				return err
+			})
+		})
+		return []string{}, entries, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc exportDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	entries, err := fromExportItems(doc.Items)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Path) == 0 {
+		return []string{}, entries, nil
+	}
+	return doc.Path[:len(doc.Path)-1], entries, nil
+}
+
+// restoreEntry writes a key or bucket from a snapshot back to path, merging it with
+// an existing bucket.
+func restoreEntry(path []string, e *entry) error {
+	return record("restore", [][]string{path}, func() error {
+		return update(func(tx *bbolt.Tx) error {
+			return writeEntry(path, e, tx)
+		})
+	})
+}
+
+type snapshotNode struct {
+	path  []string
+	entry *entry
+}
+
+func addSnapshotNodes(parent *tview.TreeNode, path []string, entries []*entry) {
+	for _, e := range entries {
+		childPath := append(slices.Clone(path), string(e.name))
+		node := tview.NewTreeNode(tview.Escape(formatName(e.name))).
+			SetReference(snapshotNode{path: childPath, entry: e})
+		if e.bucket {
+			node.SetColor(tcell.ColorGreen).Collapse()
+			addSnapshotNodes(node, childPath, e.children)
+		}
+		parent.AddChild(node)
+	}
+}
+
+// snapshotView lists the snapshots of the open database. The content of the selected
+// snapshot is shown in a tree; pressing r on a key or bucket restores it to its path.
+func snapshotView() (*tview.Grid, error) {
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	list := tview.NewList()
+	list.SetBorder(true).SetTitle(fmt.Sprintf("%d snapshots in %s", len(snapshots), snapshotDir))
+	content := tview.NewTreeView()
+	content.SetBorder(true).SetTitle("Content")
+	show := func(index int) {
+		root := tview.NewTreeNode(".").SetColor(tcell.ColorRed)
+		content.SetRoot(root).SetCurrentNode(root)
+		path, entries, err := readSnapshot(snapshots[index].file)
+		if err != nil {
+			root.SetText(tview.Escape(err.Error()))
+			return
+		}
+		addSnapshotNodes(root, path, entries)
+		// expand down to the snapshot content
+		node := root
+		for _, name := range path {
+			bucket := tview.NewTreeNode(tview.Escape(formatName([]byte(name)))).SetColor(tcell.ColorGreen)
+			bucket.SetChildren(node.GetChildren())
+			node.SetChildren([]*tview.TreeNode{bucket})
+			node = bucket
+		}
+	}
+	for _, s := range snapshots {
+		list.AddItem(s.time.Format(time.DateTime)+" "+s.operation, tview.Escape(filepath.Base(s.file)), 0, nil)
+	}
+	list.SetChangedFunc(func(index int, _, _ string, _ rune) {
+		show(index)
+	})
+	list.SetSelectedFunc(func(int, string, string, rune) {
+		app.SetFocus(content)
+	})
+	if len(snapshots) > 0 {
+		show(0)
+	}
+	content.SetSelectedFunc(func(node *tview.TreeNode) {
+		node.SetExpanded(!node.IsExpanded())
+	})
+	content.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyTAB:
+			app.SetFocus(list)
+			return nil
+		case event.Key() == tcell.KeyRune && event.Rune() == 'r':
+			selected, ok := content.GetCurrentNode().GetReference().(snapshotNode)
+			if !ok {
+				return nil
+			}
+			if readOnly {
+				showError("database is open read only")
+				return nil
+			}
+			var restoreSelected func()
+			restoreSelected = func() {
+				if err := restoreEntry(selected.path, selected.entry); err != nil {
+					showRetry(err, restoreSelected)
+					return
+				}
+				pager.RemovePage("snapshots")
+				reloadAndSetSelection(selected.path)
+				app.SetFocus(tree)
+			}
+			restoreSelected()
+			return nil
+		}
+		return event
+	})
+	grid := tview.NewGrid().
+		SetRows(0, 1).
+		SetColumns(0, 0).
+		AddItem(list, 0, 0, 1, 1, 0, 0, true).
+		AddItem(content, 0, 1, 1, 1, 0, 0, false).
+		AddItem(textView("enter: browse snapshot, r: restore selected key or bucket, tab: back to list, esc to close"),
+			1, 0, 1, 2, 0, 0, false)
+	grid.SetBorder(true).SetTitle("Restore from snapshot of " + old)
+	return grid, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// bucketStats are the statistics of a bucket, including its nested buckets.
+type bucketStats struct {
+	path  []string
+	stats bbolt.BucketStats
+}
+
+func (s bucketStats) bytes() int {
+	if s.stats.BranchAlloc+s.stats.LeafAlloc == 0 {
+		return s.stats.InlineBucketInuse
+	}
+	return s.stats.BranchAlloc + s.stats.LeafAlloc
+}
+
+// fill is the percentage of allocated page space in use, or -1 for inline buckets.
+func (s bucketStats) fill() float64 {
+	alloc := s.stats.BranchAlloc + s.stats.LeafAlloc
+	if alloc == 0 {
+		return -1
+	}
+	return float64(s.stats.BranchInuse+s.stats.LeafInuse) * 100 / float64(alloc)
+}
+
+type statsColumn struct {
+	title string
+	value func(s bucketStats) string
+	cmp   func(a, b bucketStats) int
+}
+
+func intColumn(title string, value func(s bucketStats) int) statsColumn {
+	return statsColumn{
+		title: title,
+		value: func(s bucketStats) string { return strconv.Itoa(value(s)) },
+		cmp:   func(a, b bucketStats) int { return cmp.Compare(value(a), value(b)) },
+	}
+}
+
+var statsColumns = []statsColumn{
+	{
+		title: "bucket",
+		value: func(s bucketStats) string { return displayPath(s.path) },
+		cmp:   func(a, b bucketStats) int { return slices.Compare(a.path, b.path) },
+	},
+	intColumn("keys", func(s bucketStats) int { return s.stats.KeyN }),
+	intColumn("buckets", func(s bucketStats) int { return s.stats.BucketN - 1 }),
+	intColumn("inline", func(s bucketStats) int { return s.stats.InlineBucketN }),
+	intColumn("branch pages", func(s bucketStats) int { return s.stats.BranchPageN + s.stats.BranchOverflowN }),
+	intColumn("leaf pages", func(s bucketStats) int { return s.stats.LeafPageN + s.stats.LeafOverflowN }),
+	intColumn("bytes", bucketStats.bytes),
+	{
+		title: "fill %",
+		value: func(s bucketStats) string {
+			if s.fill() < 0 {
+				return "inline"
+			}
+			return strconv.FormatFloat(s.fill(), 'f', 1, 64)
+		},
+		cmp: func(a, b bucketStats) int { return cmp.Compare(a.fill(), b.fill()) },
+	},
+}
+
+// collectStats returns the statistics of every bucket and a summary of the database.
+func collectStats() ([]bucketStats, string, error) {
+	all := []bucketStats{}
+	var walk func(path []string, bucket *bbolt.Bucket) error
+	walk = func(path []string, bucket *bbolt.Bucket) error {
+		all = append(all, bucketStats{path: path, stats: bucket.Stats()})
+		return bucket.ForEach(func(k, v []byte) error {
+			if v != nil {
+				return nil
+			}
+			return walk(append(slices.Clone(path), string(k)), bucket.Bucket(k))
+		})
+	}
+	var summary string
+	err := useDB(false, func(d *bbolt.DB) error {
+		return d.View(func(tx *bbolt.Tx) error {
+			pageSize := d.Info().PageSize
+			dbStats := d.Stats()
+			summary = fmt.Sprintf("file size %d bytes, page size %d, %d pages, %d free pages (%d bytes), %d pending pages, tx %d",
+				tx.Size(), pageSize, tx.Size()/int64(pageSize), dbStats.FreePageN, dbStats.FreeAlloc,
+				dbStats.PendingPageN, tx.ID())
+			return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+				return walk([]string{string(name)}, b)
+			})
+		})
+	})
+	return all, summary, err
+}
+
+// showStats opens the statistics view.
+func showStats() {
+	stats, err := statsView()
+	if err != nil {
+		showRetry(err, showStats)
+		return
+	}
+	pager.AddPage("stats", stats, true, true)
+	app.SetFocus(stats)
+}
+
+// statsView shows the statistics of all buckets in a table. Pressing the number of a
+// column sorts by it; pressing it again reverses the order.
+func statsView() (*tview.Grid, error) {
+	all, summary, err := collectStats()
+	if err != nil {
+		return nil, err
+	}
+	table := tview.NewTable().SetFixed(1, 1).SetSelectable(true, false)
+	sortColumn, descending := 6, true
+	fill := func() {
+		slices.SortStableFunc(all, func(a, b bucketStats) int {
+			if descending {
+				return statsColumns[sortColumn].cmp(b, a)
+			}
+			return statsColumns[sortColumn].cmp(a, b)
+		})
+		table.Clear()
+		for col, column := range statsColumns {
+			title := fmt.Sprintf("%d %s", col+1, column.title)
+			switch {
+			case col == sortColumn && descending:
+				title += " ▼"
+			case col == sortColumn:
+				title += " ▲"
+			}
+			table.SetCell(0, col, tview.NewTableCell(title).
+				SetTextColor(tcell.ColorYellow).SetSelectable(false).SetExpansion(1))
+		}
+		for row, s := range all {
+			for col, column := range statsColumns {
+				align := tview.AlignRight
+				if col == 0 {
+					align = tview.AlignLeft
+				}
+				table.SetCell(row+1, col, tview.NewTableCell(tview.Escape(column.value(s))).SetAlign(align))
+			}
+		}
+		table.Select(1, 0).ScrollToBeginning()
+	}
+	fill()
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune || event.Rune() < '1' || int(event.Rune()-'1') >= len(statsColumns) {
+			return event
+		}
+		col := int(event.Rune() - '1')
+		if col == sortColumn {
+			descending = !descending
+		} else {
+			sortColumn, descending = col, col != 0
+		}
+		fill()
+		return nil
+	})
+	grid := tview.NewGrid().
+		SetRows(1, 1, 0, 1).
+		SetColumns(0).
+		AddItem(textView("Statistics of "+old), 0, 0, 1, 1, 0, 0, false).
+		AddItem(textView(summary), 1, 0, 1, 1, 0, 0, false).
+		AddItem(table, 2, 0, 1, 1, 0, 0, true).
+		AddItem(textView("press the column number to sort, again to reverse, esc to close"), 3, 0, 1, 1, 0, 0, false)
+	grid.SetBorder(true)
+	return grid, nil
+}
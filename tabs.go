@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// dbTab holds the state of an open database. The active tab lives in the globals used
+// everywhere else; it is saved into its dbTab when another tab is shown.
+type dbTab struct {
+	db            *bbolt.DB
+	file          string
+	readOnly      bool
+	loadedTxID    int
+	stale         bool
+	tree          *tview.TreeView
+	details       *tview.TextView
+	undoJournal   []operation
+	redoJournal   []operation
+	searchPattern string
+	searchHits    []searchHit
+	searchIndex   int
+	staging       bool
+	pending       []pendingChange
+	pendingView   *tview.TextView
+	stagedUndo    []operation
+	stagedRedo    []operation
+}
+
+var (
+	tabs      = []*dbTab{{}}
+	activeTab int
+)
+
+func saveTab() {
+	*tabs[activeTab] = dbTab{
+		db: db, file: old, readOnly: readOnly, loadedTxID: loadedTxID, stale: stale,
+		tree: tree, details: details, undoJournal: undoJournal, redoJournal: redoJournal,
+		searchPattern: searchPattern, searchHits: searchHits, searchIndex: searchIndex,
+		staging: staging, pending: pending, pendingView: pendingView, stagedUndo: stagedUndo, stagedRedo: stagedRedo,
+	}
+}
+
+func loadTab(index int) {
+	t := tabs[index]
+	activeTab = index
+	db, old, readOnly, loadedTxID, stale = t.db, t.file, t.readOnly, t.loadedTxID, t.stale
+	tree, details, undoJournal, redoJournal = t.tree, t.details, t.undoJournal, t.redoJournal
+	searchPattern, searchHits, searchIndex = t.searchPattern, t.searchHits, t.searchIndex
+	staging, pending, pendingView, stagedUndo, stagedRedo = t.staging, t.pending, t.pendingView, t.stagedUndo, t.stagedRedo
+}
+
+// showMain displays the main window of the active tab.
+func showMain() {
+	setHeader()
+	// the display mode may have been changed in another tab
+	relabel(tree.GetRoot())
+	details.SetTitle("Details (" + display.String() + ")")
+	grid = mainGrid()
+	pager.AddPage("main", grid, true, true)
+	app.SetFocus(tree)
+}
+
+func selectTab(index int) {
+	if index == activeTab || index < 0 || index >= len(tabs) {
+		return
+	}
+	leaveDualPane()
+	saveTab()
+	loadTab(index)
+	showMain()
+}
+
+// tabFile is the database file of a tab; the entry of the active tab may be outdated.
+func tabFile(index int) string {
+	if index == activeTab {
+		return old
+	}
+	return tabs[index].file
+}
+
+// findTab returns the tab that has file open, or -1.
+func findTab(file string) int {
+	abs, _ := filepath.Abs(file)
+	for i := range tabs {
+		if other, _ := filepath.Abs(tabFile(i)); other == abs {
+			return i
+		}
+	}
+	return -1
+}
+
+// openTab opens file in a new tab, or shows the tab that already has it open.
+func openTab(file string, ro bool) error {
+	if i := findTab(file); i >= 0 {
+		selectTab(i)
+		return nil
+	}
+	leaveDualPane()
+	saveTab()
+	previous := activeTab
+	tabs = append(tabs, &dbTab{})
+	// start from an empty state so the database of the previous tab stays open
+	loadTab(len(tabs) - 1)
+	if err := InitDatabase(file, ro); err != nil {
+		tabs = tabs[:len(tabs)-1]
+		loadTab(previous)
+		setHeader()
+		return err
+	}
+	details = newDetails()
+	tree = newTree(details)
+	showMain()
+	return nil
+}
+
+// closeTab closes the database of the active tab and shows the next one.
+func closeTab() error {
+	if len(tabs) == 1 {
+		return errors.New("this is the only open database, press esc to quit")
+	}
+	if len(pending) > 0 {
+		return errors.New("commit or discard the pending changes first (" + boundKey("transaction") + ")")
+	}
+	leaveDualPane()
+	CloseDatabase()
+	tabs = slices.Delete(tabs, activeTab, activeTab+1)
+	loadTab(min(activeTab, len(tabs)-1))
+	showMain()
+	return nil
+}
+
+// anyPending reports whether any tab has pending changes.
+func anyPending() bool {
+	saveTab()
+	return slices.ContainsFunc(tabs, func(t *dbTab) bool { return len(t.pending) > 0 })
+}
+
+// tabLine lists the open databases for the header, the active one in brackets.
+func tabLine() string {
+	names := []string{}
+	for i := range tabs {
+		name := fmt.Sprintf("%d %s", i+1, filepath.Base(tabFile(i)))
+		if i == activeTab {
+			name = "[" + name + "]"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " | ")
+}
+
+// tabNames are the database files of all tabs for the target database selectors.
+func tabNames() []string {
+	names := []string{}
+	for i := range tabs {
+		names = append(names, tabFile(i))
+	}
+	return names
+}
+
+// inTab runs fn with tab index as the active tab without showing it.
+func inTab(index int, fn func() error) error {
+	current := activeTab
+	saveTab()
+	loadTab(index)
+	defer func() {
+		saveTab()
+		loadTab(current)
+		// reloading the tree of the other tab shows its file in the header
+		setHeader()
+	}()
+	return fn()
+}
+
+// copyToTab copies the key or bucket of node to newpath in the database of another tab.
+// Existing keys and buckets are not overwritten. The copy is recorded in the undo
+// journal of the target database and selected in its tree.
+func copyToTab(node dbNode, target int, newpath []string) error {
+	if len(node.path) == 0 {
+		return errors.New("cannot copy root node")
+	}
+	if len(newpath) == 0 {
+		return errors.New("invalid destination path")
+	}
+	var source *entry
+	err := view(func(tx *bbolt.Tx) error {
+		var err error
+		source, err = readEntry(node.path, tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		return errors.New(displayPath(node.path) + " does not exist")
+	}
+	copied := *source
+	copied.name = []byte(newpath[len(newpath)-1])
+	return inTab(target, func() error {
+		if readOnly {
+			return errors.New(old + " is open read only")
+		}
+		err := record("copy", [][]string{newpath}, func() error {
+			return update(func(tx *bbolt.Tx) error {
+				existing, err := readEntry(newpath, tx)
+				if err != nil {
+					return err
+				}
+				if existing != nil {
+					return fmt.Errorf("%s already exists in %s", displayPath(newpath), old)
+				}
+				return writeEntry(newpath, &copied, tx)
+			})
+		})
+		if err != nil {
+			return err
+		}
+		reloadAndSetSelection(newpath)
+		return nil
+	})
+}
+
+// moveToTab copies node to another tab and then deletes it from the active database.
+func moveToTab(node dbNode, target int, newpath []string) error {
+	if err := copyToTab(node, target, newpath); err != nil {
+		return err
+	}
+	return record("move", [][]string{node.path}, func() error {
+		if node.kind == "bucket" {
+			if err := takeSnapshot("move", node.path); err != nil {
+				return err
+			}
+		}
+		return update(func(tx *bbolt.Tx) error {
+			return removeEntry(node.path, tx)
+		})
+	})
+}
+
+// targetDropDown adds a selector for the database to copy or move to if more than one
+// database is open. It returns the index of the form item, or -1.
+func targetDropDown(form *tview.Form) int {
+	if len(tabs) < 2 {
+		return -1
+	}
+	form.AddDropDown("target database", tabNames(), activeTab, nil)
+	return form.GetFormItemCount() - 1
+}
+
+// targetTab is the tab selected in the target database selector.
+func targetTab(form *tview.Form, item int) int {
+	if item < 0 {
+		return activeTab
+	}
+	target, _ := form.GetFormItem(item).(*tview.DropDown).GetCurrentOption()
+	return target
+}
+
+// tabRows is the extra height of copy and move dialogs with a target database selector.
+func tabRows() int {
+	if len(tabs) < 2 {
+		return 0
+	}
+	return 2
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rivo/tview"
+	"go.etcd.io/bbolt"
+)
+
+// pendingChange is an operation staged in transaction mode. apply holds the updates
+// it made, which are replayed on every read and applied together on commit.
+type pendingChange struct {
+	name  string
+	paths [][]string
+	apply []func(tx *bbolt.Tx) error
+}
+
+var (
+	staging     bool
+	pending     []pendingChange
+	inProgress  *pendingChange
+	pendingView *tview.TextView
+	// journals at the start of transaction mode, restored on discard
+	stagedUndo []operation
+	stagedRedo []operation
+)
+
+// replay applies the pending changes, including those of the operation in progress.
+func replay(tx *bbolt.Tx) error {
+	changes := pending
+	if inProgress != nil {
+		changes = append(slices.Clone(pending), *inProgress)
+	}
+	for _, change := range changes {
+		for _, apply := range change.apply {
+			if err := apply(tx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// view runs fn in a read transaction. In transaction mode fn sees the pending changes,
+// which are applied in a write transaction that is rolled back afterwards.
+func view(fn func(tx *bbolt.Tx) error) error {
+	if !staging || (len(pending) == 0 && inProgress == nil) {
+		return useDB(false, func(d *bbolt.DB) error {
+			return d.View(func(tx *bbolt.Tx) error {
+				noteTxID(tx.ID())
+				return fn(tx)
+			})
+		})
+	}
+	return useDB(true, func(d *bbolt.DB) error {
+		tx, err := d.Begin(true)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback() //nolint:errcheck
+		noteTxID(tx.ID() - 1)
+		if err := replay(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// update runs fn in a write transaction. In transaction mode fn is checked against the
+// pending changes and added to them instead of being committed.
+func update(fn func(tx *bbolt.Tx) error) error {
+	if !staging {
+		return commit(fn)
+	}
+	if err := view(fn); err != nil {
+		return err
+	}
+	if inProgress == nil {
+		pending = append(pending, pendingChange{name: "change", apply: []func(tx *bbolt.Tx) error{fn}})
+		showPending()
+		return nil
+	}
+	inProgress.apply = append(inProgress.apply, fn)
+	return nil
+}
+
+// commit runs fn in a write transaction unless another process changed the file.
+func commit(fn func(tx *bbolt.Tx) error) error {
+	stopCompactions()
+	return useDB(true, func(d *bbolt.DB) error {
+		id := 0
+		err := d.Update(func(tx *bbolt.Tx) error {
+			if err := checkTxID(tx.ID() - 1); err != nil {
+				return err
+			}
+			id = tx.ID()
+			return fn(tx)
+		})
+		if err == nil {
+			loadedTxID = id
+		}
+		return err
+	})
+}
+
+// stageChange collects the updates made by fn into one pending change.
+func stageChange(name string, paths [][]string, fn func() error) error {
+	if !staging {
+		return fn()
+	}
+	inProgress = &pendingChange{name: name, paths: paths}
+	err := fn()
+	if len(inProgress.apply) > 0 {
+		pending = append(pending, *inProgress)
+	}
+	inProgress = nil
+	showPending()
+	return err
+}
+
+func startTransaction() {
+	staging = true
+	pending = nil
+	stagedUndo = slices.Clone(undoJournal)
+	stagedRedo = slices.Clone(redoJournal)
+}
+
+// commitTransaction applies all pending changes in one transaction and leaves transaction mode.
+// Nothing is written if one of them fails.
+func commitTransaction() error {
+	if err := commit(replay); err != nil {
+		return err
+	}
+	staging = false
+	pending = nil
+	return nil
+}
+
+// discardTransaction drops the pending changes and the undo history made since.
+func discardTransaction() {
+	if staging {
+		undoJournal = stagedUndo
+		redoJournal = stagedRedo
+	}
+	staging = false
+	pending = nil
+	inProgress = nil
+}
+
+// isPending reports whether path, one of its buckets or its content has a pending change.
+func isPending(path []string) bool {
+	for _, change := range pending {
+		for _, changed := range change.paths {
+			n := min(len(path), len(changed))
+			if n > 0 && slices.Equal(path[:n], changed[:n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func showPending() {
+	if pendingView == nil {
+		return
+	}
+	lines := []string{}
+	for i, change := range pending {
+		paths := []string{}
+		for _, path := range change.paths {
+			paths = append(paths, displayPath(path))
+		}
+		lines = append(lines, fmt.Sprintf("%d %s\n  %s", i+1, change.name, strings.Join(paths, "\n  ")))
+	}
+	pendingView.SetText(tview.Escape(strings.Join(lines, "\n")))
+	pendingView.SetTitle(fmt.Sprintf("Pending changes (%d)", len(pending)))
+}
+
+// transactionDialog asks whether to commit or discard the pending changes.
+func transactionDialog() *tview.Modal {
+	return tview.NewModal().
+		SetText(fmt.Sprintf("%d pending changes", len(pending))).
+		AddButtons([]string{"Commit", "Discard", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			pager.RemovePage("transaction")
+			switch label {
+			case "Commit":
+				if err := commitTransaction(); err != nil {
+					showError("commit failed, nothing written: " + err.Error())
+					return
+				}
+			case "Discard":
+				discardTransaction()
+			default:
+				app.SetFocus(tree)
+				return
+			}
+			grid = mainGrid()
+			pager.AddPage("main", grid, true, true)
+			reloadTree()
+			app.SetFocus(tree)
+		})
+}
@@ -3,184 +3,307 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
-func newTree(detail *tview.TextView) *tview.TreeView { //nolint:funlen
-	treeKeys := []key{
-		{"c", "(c)opy key or bucket"},
-		{"b", "create new (b)ucket"},
-		{"d", "(d)elete key or bucket"},
-		{"e", "(e)mpty bucket or (e)dit key"},
-		{"a", "(a)dd new key"},
-		{"m", "(m)ove key or bucket"},
-		{"o", "(o)pen file selection"},
-		{"r", "(r)ename key or bucket"},
-		{"s", "(s)earch for key or bucket"},
-		{"x", "e(x)pand all nodes"},
-		{"?", "show help"},
-		{"Enter", "expand or colapse node"},
-		{"Ctrl-R", "reload database"},
-		{"Ctrl-C", "colapse all nodes"},
-		{"Ctrl-X", "expand all nodes"},
-	}
+// pageSize is the number of entries loaded each time a bucket is expanded.
+const pageSize = 1000
 
+func newTree(detail *tview.TextView) *tview.TreeView { //nolint:funlen
 	rootDir := "."
 	root := tview.NewTreeNode(rootDir).
 		SetColor(tcell.ColorRed)
-	root.SetChildren(getNodes())
+	loadChildren(root)
 	tree := tview.NewTreeView().
 		SetRoot(root).
 		SetCurrentNode(root)
 	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		if reference, ok := node.GetReference().(dbNode); ok && reference.kind == "more" {
+			loadMore(tree, node)
+			updateDetail(detail, tree.GetCurrentNode())
+			return
+		}
+		if !node.IsExpanded() {
+			loadChildren(node)
+		}
 		node.SetExpanded(!node.IsExpanded())
 		updateDetail(detail, node)
+		if dualPane {
+			markDifferences()
+		}
 	})
 	tree.SetChangedFunc(func(node *tview.TreeNode) {
 		updateDetail(detail, node)
+		if dualPane {
+			markDifferences()
+		}
 	})
 	tree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		log.Println("tree key handler", tcell.KeyNames[event.Key()])
-		switch event.Key() {
-		// callapse all nodes
-		case tcell.KeyCtrlC:
-			tree.GetRoot().CollapseAll()
-			// reload database
-		case tcell.KeyCtrlR:
-			reloadDB()
-			root.SetChildren(getNodes())
-			tree.SetRoot(root)
-			// expand all nodes
-		case tcell.KeyCtrlX:
-			tree.GetRoot().ExpandAll()
-			// exit app
-		case tcell.KeyEsc:
-			app.Stop()
-			// change focue
-		case tcell.KeyTAB:
+		log.Println("tree key handler", event.Name())
+		bound := actionFor("tree", event)
+		if bound == nil {
+			return event
+		}
+		if readOnly && bound.mutating {
+			showError("database is open read only")
+			return nil
+		}
+		switch bound.id {
+		case "copy":
+			node := getCurrentNode()
+			copied := dialog(copyForm(node, "dialog"), 60, 12+tabRows())
+			pager.AddPage("dialog", copied, true, true)
+		case "add-bucket":
+			node := getCurrentNode()
+			bucket := dialog(addBucketForm(node, "dialog"), 60, 12)
+			pager.AddPage("dialog", bucket, true, true)
+		case "delete":
+			node := getCurrentNode()
+			if node.path == nil {
+				showError("cannot delete root node")
+				return nil
+			}
+			deleteItem := modal(deleteForm(node, "dialog"), 40, 7)
+			pager.AddPage("dialog", deleteItem, true, true)
+		// empty bucket or edit key
+		case "edit":
+			node := getCurrentNode()
+			if node.path == nil {
+				showError("not applicable to root node")
+				return nil
+			}
+			if node.kind == "bucket" { //nolint:goconst
+				empty := dialog(emptyForm(node, "dialog"), 60, 7)
+				pager.AddPage("dialog", empty, true, true)
+				log.Println("focus empty modal")
+				app.SetFocus(empty)
+				return nil
+			}
+			log.Println("edit key")
+			edit := dialog(editForm(node, "dialog"), 60, 20)
+			pager.AddPage("dialog", edit, true, true)
+		case "transaction":
+			if !staging {
+				startTransaction()
+				grid = mainGrid()
+				pager.AddPage("main", grid, true, true)
+				app.SetFocus(tree)
+				return nil
+			}
+			commit := transactionDialog()
+			pager.AddPage("transaction", commit, true, true)
+			app.SetFocus(commit)
+		case "edit-external":
+			node := getCurrentNode()
+			if node.kind != "key" {
+				showError("only keys can be edited")
+				return nil
+			}
+			editExternal(node)
+		case "add-key":
+			node := getCurrentNode()
+			if node.path == nil {
+				showError("cannot add key to root")
+				return nil
+			}
+			key := modal(addKeyForm(node, "dialog"), 60, 22)
+			pager.AddPage("dialog", key, true, true)
+		case "move":
+			node := getCurrentNode()
+			if node.path == nil {
+				showError("cannot move root node")
+				return nil
+			}
+			move := modal(moveForm(node, "dialog"), 60, 10+tabRows())
+			pager.AddPage("dialog", move, true, true)
+		case "open":
+			file := dialog(newFiles(), 60, 30)
+			pager.AddPage("file", file, true, true)
+			app.SetFocus(file)
+		case "rename":
+			node := getCurrentNode()
+			if node.path == nil {
+				showError("cannot rename root node")
+				return nil
+			}
+			rename := modal(renameForm(node, "dialog"), 40, 10)
+			pager.AddPage("dialog", rename, true, true)
+		case "search":
+			search := modal(searchForm("dialog"), 40, 10)
+			pager.AddPage("dialog", search, true, true)
+		case "find":
+			find := modal(findForm("dialog"), 60, 13)
+			pager.AddPage("dialog", find, true, true)
+		case "find-results":
+			if len(searchHits) == 0 {
+				showError("no search results")
+				return nil
+			}
+			results := modal(searchResults("results"), 80, 20)
+			pager.AddPage("results", results, true, true)
+			app.SetFocus(results)
+		case "next-match":
+			gotoHit(searchIndex + 1)
+		case "previous-match":
+			gotoHit(searchIndex - 1)
+		case "display-mode":
+			nextDisplayMode()
+			relabel(tree.GetRoot())
+			detail.SetTitle("Details (" + display.String() + ")")
+			updateDetail(detail, tree.GetCurrentNode())
+		case "stats":
+			showStats()
+		case "check":
+			report := checkView()
+			pager.AddPage("check", report, true, true)
+			app.SetFocus(report)
+		case "compact":
+			compacted := modal(compactForm("dialog"), 60, 13)
+			pager.AddPage("dialog", compacted, true, true)
+		case "two-pane":
+			if dualPane {
+				leaveDualPane()
+				showMain()
+				return nil
+			}
+			startDualPane()
+		case "switch-pane":
+			if dualPane {
+				activatePane(1 - activePane)
+				return nil
+			}
 			app.SetFocus(detail)
-			// key handling
-		case tcell.KeyRune:
-			log.Println("tree key handler, runes", event.Rune())
-			switch event.Rune() {
-			// collapse node
-			case 'c':
-				node := getCurrentNode()
-				copied := dialog(copyForm(node, "dialog"), 60, 12)
-				pager.AddPage("dialog", copied, true, true)
-			// add bucket
-			case 'b':
-				node := getCurrentNode()
-				bucket := dialog(addBucketForm(node, "dialog"), 60, 12)
-				pager.AddPage("dialog", bucket, true, true)
-				return nil
-			// delete bucket/key
-			case 'd':
-				node := getCurrentNode()
-				if node.path == nil {
-					showError("cannot delete root node")
-					return nil
-				}
-				deleteItem := modal(deleteForm(node, "dialog"), 40, 7)
-				pager.AddPage("dialog", deleteItem, true, true)
-				return nil
-			// empty bucket or edit key
-			case 'e':
-				node := getCurrentNode()
-				if node.path == nil {
-					showError("not applicable to root node")
-					return nil
-				}
-				if node.kind == "bucket" { //nolint:goconst
-					empty := dialog(emptyForm(node, "dialog"), 60, 7)
-					pager.AddPage("dialog", empty, true, true)
-					log.Println("focus empty modal")
-					app.SetFocus(empty)
-					return nil
-				}
-				log.Println("edit key")
-				edit := dialog(editForm(node, "dialog"), 60, 20)
-				pager.AddPage("dialog", edit, true, true)
-				return nil
-			// add key
-			case 'a':
-				node := getCurrentNode()
-				if node.path == nil {
-					showError("cannot add key to root")
-					pager.ShowPage("error")
-					return nil
-				}
-				key := modal(addKeyForm(node, "dialog"), 60, 22)
-				pager.AddPage("dialog", key, true, true)
-				return nil
-			// move bucket/key
-			case 'm':
-				node := getCurrentNode()
-				if node.path == nil {
-					showError("cannot move root node")
-					pager.ShowPage("error")
-					return nil
-				}
-				move := modal(moveForm(node, "dialog"), 60, 10)
-				pager.AddPage("dialog", move, true, true)
-				return nil
-			// open filepicker
-			case 'o':
-				file := dialog(newFiles(), 60, 30)
-				pager.AddPage("file", file, true, true)
-				app.SetFocus(file)
-				return nil
-			// rename bucket/key
-			case 'r':
-				node := getCurrentNode()
-				if node.path == nil {
-					showError("cannot rename root node")
-					pager.ShowPage("error")
-					return nil
-				}
-				rename := modal(renameForm(node, "dialog"), 40, 10)
-				pager.AddPage("dialog", rename, true, true)
-				return nil
-			case 's':
-				search := modal(searchForm("dialog"), 40, 10)
-				pager.AddPage("dialog", search, true, true)
-				return nil
-			// show help
-			case '?':
-				help := helpDialog("Key Bindings", 100, 20, treeKeys, treeMoveKeys)
-				pager.AddPage("help", help, true, true)
-				app.SetFocus(help)
+		case "pane-copy":
+			if !dualPane {
+				return nil
+			}
+			transfer(false)
+		case "pane-move":
+			if !dualPane {
+				return nil
+			}
+			transfer(true)
+		case "previous-tab":
+			selectTab(activeTab - 1)
+		case "next-tab":
+			selectTab(activeTab + 1)
+		case "close-tab":
+			if err := closeTab(); err != nil {
+				showError(err.Error())
+			}
+		case "new-database":
+			create := modal(newDatabaseForm("", false, "dialog"), 60, 9)
+			pager.AddPage("dialog", create, true, true)
+		case "backup":
+			backup := modal(backupForm("dialog"), 70, 7)
+			pager.AddPage("dialog", backup, true, true)
+		case "restore":
+			if snapshotDir == "" {
+				showError("snapshots are not enabled, start with -snapshots dir")
+				return nil
+			}
+			snapshots, err := snapshotView()
+			if err != nil {
+				showError(err.Error())
+				return nil
+			}
+			pager.AddPage("snapshots", snapshots, true, true)
+			app.SetFocus(snapshots)
+		case "diff":
+			node := getCurrentNode()
+			compared := modal(diffForm(node, "dialog"), 70, 13)
+			pager.AddPage("dialog", compared, true, true)
+		case "export":
+			node := getCurrentNode()
+			export := modal(exportForm(node, "dialog"), 60, 9)
+			pager.AddPage("dialog", export, true, true)
+		case "import":
+			node := getCurrentNode()
+			imported := modal(importForm(node, "dialog"), 60, 11)
+			pager.AddPage("dialog", imported, true, true)
+		case "undo":
+			revert(undo)
+		case "redo":
+			revert(redo)
+		case "command-line":
+			showCommandLine("")
+		case "command-palette":
+			palette := modal(commandPalette("palette"), 70, 20)
+			pager.AddPage("palette", palette, true, true)
+			app.SetFocus(palette)
+		case "help":
+			keys := keyTable("tree")
+			help := helpDialog("Key Bindings", 100, len(keys)+4, keys, treeMoveKeys)
+			pager.AddPage("help", help, true, true)
+			app.SetFocus(help)
+		case "reload":
+			reloadTree()
+		case "collapse-all":
+			tree.GetRoot().CollapseAll()
+		case "expand-all":
+			expandAll(tree.GetRoot())
+		case "quit":
+			if anyPending() {
+				showError("commit or discard the pending changes first (" + boundKey("transaction") + ")")
 				return nil
 			}
+			app.Stop()
 		}
-		return event
+		return nil
 	})
-	tree.SetBorder(true).SetTitle("bbolt db viewer").SetTitleAlign(tview.AlignCenter)
+	tree.SetBorder(true).SetTitle(treeTitle()).SetTitleAlign(tview.AlignCenter)
 	return tree
 }
 
-func updateDetail(detail *tview.TextView, node *tview.TreeNode) {
-	reference := node.GetReference()
-	value := ""
-	if reference != nil {
-		value = strings.Join(reference.([]string), " -> ")
+// revert undoes or redoes the last operation and selects its first path.
+func revert(fn func() (operation, error)) {
+	op, err := fn()
+	if err != nil {
+		showRetry(err, func() { revert(fn) })
+		return
 	}
-	entry, ok := dbNodes[value]
+	reloadAndSetSelection(op.changes[0].path)
+}
+
+func treeTitle() string {
+	if readOnly {
+		return "bbolt db viewer (read only)"
+	}
+	return "bbolt db viewer"
+}
+
+func updateDetail(detail *tview.TextView, node *tview.TreeNode) {
+	entry, ok := node.GetReference().(dbNode)
 	if !ok {
-		log.Println("invalid value", value)
+		detail.SetText("")
 		return
 	}
-	if entry.kind == "bucket" {
+	var value string
+	switch entry.kind {
+	case "bucket":
 		value = fmt.Sprintf("Bucket:\n\nPath: %s\nName: %s",
-			strings.Join(entry.path, " -> "), string(entry.name))
-	} else {
-		value = fmt.Sprintf("Key:\n\nPath: %s\nName: %s\n\nValue:\n\n%s",
-			strings.Join(entry.path, " -> "), string(entry.name), prettyString(entry.value))
+			displayPath(entry.path), formatName(entry.name))
+	case "more":
+		value = fmt.Sprintf("More entries in bucket %s\n\npress enter to load the next %d",
+			displayPath(entry.path), pageSize)
+	default:
+		data, err := getValue(entry.path)
+		if errors.Is(err, errLocked) {
+			detail.SetText(err.Error())
+			showRetry(err, func() { updateDetail(detail, tree.GetCurrentNode()) })
+			return
+		}
+		if err != nil {
+			log.Println("invalid value", entry.path, err)
+			return
+		}
+		value = fmt.Sprintf("Key:\n\nPath: %s\nName: %s\n\nValue (%d bytes):\n\n%s",
+			displayPath(entry.path), formatName(entry.name), len(data), formatValue(data))
 	}
 	detail.SetText(value)
 }
@@ -205,10 +328,107 @@ func modal(p tview.Primitive, w, h int) tview.Primitive { //nolint:ireturn
 	return modal
 }
 
+// newTreeNode creates a tree node for a database entry. Children of buckets are loaded on expansion.
+func newTreeNode(node dbNode) *tview.TreeNode {
+	treeNode := tview.NewTreeNode(tview.Escape(formatName(node.name))).SetReference(node).SetSelectable(true)
+	if node.kind == "bucket" {
+		treeNode.Collapse()
+	}
+	return treeNode.SetColor(nodeColor(node))
+}
+
+func nodeColor(node dbNode) tcell.Color {
+	switch {
+	case staging && isPending(node.path):
+		return tcell.ColorOrange
+	case node.kind == "bucket":
+		return tcell.ColorGreen
+	default:
+		return tview.Styles.PrimaryTextColor
+	}
+}
+
+// loadChildren reads the entries of a bucket node (or the root buckets for the root node)
+// from the database. Nothing is done if the entries have already been loaded.
+// If the bucket holds more than pageSize entries, a "more" node is added after the last one.
+func loadChildren(treeNode *tview.TreeNode) {
+	var path []string
+	if reference, ok := treeNode.GetReference().(dbNode); ok {
+		if reference.kind != "bucket" {
+			return
+		}
+		path = reference.path
+	}
+	var after []byte
+	children := treeNode.GetChildren()
+	if len(children) > 0 {
+		last := children[len(children)-1]
+		reference := last.GetReference().(dbNode)
+		if reference.kind != "more" {
+			return
+		}
+		treeNode.RemoveChild(last)
+		after = reference.name
+	}
+	nodes, more, err := getChildren(path, after, pageSize)
+	if err != nil {
+		log.Println("load children", path, err)
+		if errors.Is(err, errLocked) {
+			showRetry(err, func() { loadChildren(treeNode) })
+		}
+		return
+	}
+	for _, node := range nodes {
+		treeNode.AddChild(newTreeNode(node))
+	}
+	if more {
+		treeNode.AddChild(tview.NewTreeNode("... more").
+			SetReference(dbNode{path: path, kind: "more", name: nodes[len(nodes)-1].name}).
+			SetSelectable(true).SetColor(tcell.ColorYellow))
+	}
+}
+
+// loadMore replaces a "more" node with the next page of entries and selects the first of them.
+func loadMore(tree *tview.TreeView, node *tview.TreeNode) {
+	path := tree.GetPath(node)
+	if len(path) < 2 {
+		return
+	}
+	parent := path[len(path)-2]
+	index := len(parent.GetChildren()) - 1
+	loadChildren(parent)
+	if children := parent.GetChildren(); index < len(children) {
+		tree.SetCurrentNode(children[index])
+	}
+}
+
+// relabel updates the text of node and its loaded children after the display mode changed.
+func relabel(node *tview.TreeNode) {
+	if reference, ok := node.GetReference().(dbNode); ok && reference.kind != "more" {
+		node.SetText(tview.Escape(formatName(reference.name)))
+	}
+	for _, child := range node.GetChildren() {
+		relabel(child)
+	}
+}
+
+func expandAll(node *tview.TreeNode) {
+	loadChildren(node)
+	node.Expand()
+	for _, child := range node.GetChildren() {
+		expandAll(child)
+	}
+}
+
 func selectNode(path []string) {
 	node := tree.GetRoot()
 	for _, name := range path {
-		node = getChild(node, name)
+		loadChildren(node)
+		child := getChild(node, name)
+		if child == nil {
+			break
+		}
+		node = child
 	}
 	for _, n := range tree.GetPath(node) {
 		n.Expand()
@@ -218,38 +438,62 @@ func selectNode(path []string) {
 	fn(node)
 }
 
+// getChild finds the named child, loading further pages of the bucket as required.
 func getChild(node *tview.TreeNode, name string) *tview.TreeNode {
-	children := node.GetChildren()
-	for _, child := range children {
-		if child.GetText() == name {
-			return child
+	for {
+		children := node.GetChildren()
+		for _, child := range children {
+			reference := child.GetReference().(dbNode)
+			if reference.kind != "more" && string(reference.name) == name {
+				return child
+			}
+		}
+		if len(children) == 0 || children[len(children)-1].GetReference().(dbNode).kind != "more" {
+			return nil
 		}
+		loadChildren(node)
 	}
-	return nil
 }
 
-func reloadAndSetSelection(path []string) {
-	reloadDB()
+func reloadTree() {
+	if err := reloadDB(); err != nil {
+		showRetry(err, reloadTree)
+		return
+	}
 	root := tree.GetRoot()
-	root.SetChildren(getNodes())
-	tree.SetRoot(root)
+	root.ClearChildren()
+	loadChildren(root)
+	tree.SetCurrentNode(root)
+}
+
+func reloadAndSetSelection(path []string) {
+	reloadTree()
 	selectNode(path)
 }
 
 func getCurrentNode() dbNode {
 	treeNode := tree.GetCurrentNode()
-	reference := treeNode.GetReference()
-	if reference == nil {
+	node, ok := treeNode.GetReference().(dbNode)
+	if !ok {
 		return dbNode{
 			path: nil,
 			kind: "bucket",
 		}
 	}
-	path := reference.([]string)
-	node, ok := dbNodes[strings.Join(path, " -> ")]
-	if !ok {
-		log.Println("involid node", path)
-		return dbNode{}
+	switch node.kind {
+	case "more":
+		// the more node acts on the bucket it belongs to
+		if len(node.path) == 0 {
+			return dbNode{path: nil, kind: "bucket"}
+		}
+		return dbNode{path: node.path, kind: "bucket", name: []byte(node.path[len(node.path)-1])}
+	case "key":
+		value, err := getValue(node.path)
+		if err != nil {
+			log.Println("invalid node", node.path, err)
+			return dbNode{}
+		}
+		node.value = value
 	}
 	return node
 }
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"slices"
+
+	"go.etcd.io/bbolt"
+)
+
+// entry holds a copy of a key or a bucket and all of its content.
+type entry struct {
+	name     []byte
+	value    []byte
+	bucket   bool
+	children []*entry
+}
+
+// change is the state of a path before and after an operation. A nil entry means the path did not exist.
+type change struct {
+	path   []string
+	before *entry
+	after  *entry
+}
+
+type operation struct {
+	name    string
+	changes []change
+}
+
+var (
+	undoJournal []operation
+	redoJournal []operation
+)
+
+func clearJournal() {
+	undoJournal = nil
+	redoJournal = nil
+}
+
+// record runs fn, which modifies the database at the given paths, and adds the
+// changes it made to the undo journal.
+func record(name string, paths [][]string, fn func() error) error {
+	paths = affectedPaths(paths)
+	before, err := readEntries(paths)
+	if err != nil {
+		return err
+	}
+	fnErr := stageChange(name, paths, fn)
+	after, err := readEntries(paths)
+	if err != nil {
+		return errors.Join(fnErr, err)
+	}
+	if reflect.DeepEqual(before, after) {
+		return fnErr
+	}
+	op := operation{name: name}
+	for i, path := range paths {
+		op.changes = append(op.changes, change{path: path, before: before[i], after: after[i]})
+	}
+	undoJournal = append(undoJournal, op)
+	redoJournal = nil
+	log.Println("recorded", name, paths)
+	return fnErr
+}
+
+// undo reverts the last recorded operation and returns it.
+func undo() (operation, error) {
+	if len(undoJournal) == 0 {
+		return operation{}, errors.New("nothing to undo")
+	}
+	op := undoJournal[len(undoJournal)-1]
+	if err := restore(op, true); err != nil {
+		return op, err
+	}
+	undoJournal = undoJournal[:len(undoJournal)-1]
+	redoJournal = append(redoJournal, op)
+	return op, nil
+}
+
+// redo applies the last undone operation again and returns it.
+func redo() (operation, error) {
+	if len(redoJournal) == 0 {
+		return operation{}, errors.New("nothing to redo")
+	}
+	op := redoJournal[len(redoJournal)-1]
+	if err := restore(op, false); err != nil {
+		return op, err
+	}
+	redoJournal = redoJournal[:len(redoJournal)-1]
+	undoJournal = append(undoJournal, op)
+	return op, nil
+}
+
+// restore sets each path of the operation to its state before (or after) the operation.
+// The database must still hold the opposite state, otherwise nothing is changed.
+func restore(op operation, before bool) error {
+	log.Println("restore", op.name, "before", before)
+	name := "redo " + op.name
+	if before {
+		name = "undo " + op.name
+	}
+	paths := [][]string{}
+	for _, change := range op.changes {
+		paths = append(paths, change.path)
+	}
+	return stageChange(name, paths, func() error {
+		return restoreChanges(op, before)
+	})
+}
+
+func restoreChanges(op operation, before bool) error {
+	return update(func(tx *bbolt.Tx) error {
+		for _, change := range op.changes {
+			expected := change.after
+			if !before {
+				expected = change.before
+			}
+			current, err := readEntry(change.path, tx)
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(current, expected) {
+				return errors.New("cannot " + op.name + ": database has been changed since")
+			}
+		}
+		for _, change := range op.changes {
+			if err := removeEntry(change.path, tx); err != nil {
+				return err
+			}
+		}
+		for _, change := range op.changes {
+			want := change.before
+			if !before {
+				want = change.after
+			}
+			if want == nil {
+				continue
+			}
+			if err := writeEntry(change.path, want, tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// affectedPaths replaces each path by its shortest prefix that does not exist, so buckets
+// created by an operation are recorded too, and drops paths contained in another one.
+func affectedPaths(paths [][]string) [][]string {
+	result := [][]string{}
+	view(func(tx *bbolt.Tx) error { //nolint:errcheck
+		for _, path := range paths {
+			for i := 1; i < len(path); i++ {
+				if _, err := getBucket(path[:i], tx); err != nil {
+					path = path[:i]
+					break
+				}
+			}
+			if !slices.ContainsFunc(result, func(other []string) bool { return slices.Equal(other, path) }) {
+				result = append(result, slices.Clone(path))
+			}
+		}
+		return nil
+	})
+	all := slices.Clone(result)
+	return slices.DeleteFunc(result, func(path []string) bool {
+		for _, other := range all {
+			if len(other) < len(path) && slices.Equal(other, path[:len(other)]) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func readEntries(paths [][]string) ([]*entry, error) {
+	entries := []*entry{}
+	err := view(func(tx *bbolt.Tx) error {
+		for _, path := range paths {
+			e, err := readEntry(path, tx)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// readEntry copies the key or bucket at path. It returns nil if the path does not exist.
+func readEntry(path []string, tx *bbolt.Tx) (*entry, error) {
+	if len(path) == 0 {
+		return nil, errors.New("invalid path")
+	}
+	name := []byte(path[len(path)-1])
+	parent, err := getParentBucket(path, tx)
+	if err != nil {
+		return nil, nil //nolint:nilerr,nilnil
+	}
+	var bucket *bbolt.Bucket
+	if parent == nil {
+		bucket = tx.Bucket(name)
+	} else {
+		bucket = parent.Bucket(name)
+		if bucket == nil {
+			value := parent.Get(name)
+			if value == nil {
+				return nil, nil //nolint:nilnil
+			}
+			return &entry{name: name, value: slices.Clone(value)}, nil
+		}
+	}
+	if bucket == nil {
+		return nil, nil //nolint:nilnil
+	}
+	return copyEntry(name, bucket)
+}
+
+func copyEntry(name []byte, bucket *bbolt.Bucket) (*entry, error) {
+	e := &entry{name: slices.Clone(name), bucket: true}
+	err := bucket.ForEach(func(k, v []byte) error {
+		if v == nil {
+			child, err := copyEntry(k, bucket.Bucket(k))
+			if err != nil {
+				return err
+			}
+			e.children = append(e.children, child)
+			return nil
+		}
+		e.children = append(e.children, &entry{name: slices.Clone(k), value: slices.Clone(v)})
+		return nil
+	})
+	return e, err
+}
+
+// removeEntry deletes the key or bucket at path if it exists.
+func removeEntry(path []string, tx *bbolt.Tx) error {
+	name := []byte(path[len(path)-1])
+	parent, err := getParentBucket(path, tx)
+	if err != nil {
+		return nil //nolint:nilerr
+	}
+	if parent == nil {
+		if tx.Bucket(name) == nil {
+			return nil
+		}
+		return tx.DeleteBucket(name)
+	}
+	if parent.Bucket(name) != nil {
+		return parent.DeleteBucket(name)
+	}
+	if parent.Get(name) != nil {
+		return parent.Delete(name)
+	}
+	return nil
+}
+
+// writeEntry creates the key or bucket at path, including any missing parent buckets.
+// An existing bucket is merged with the content of the entry.
+func writeEntry(path []string, e *entry, tx *bbolt.Tx) error {
+	parent, err := createParentBucket(path, tx)
+	if err != nil {
+		return err
+	}
+	if !e.bucket {
+		if parent == nil {
+			return errors.New("cannot create key in root bucket")
+		}
+		return parent.Put(e.name, e.value)
+	}
+	var bucket *bbolt.Bucket
+	if parent == nil {
+		bucket, err = tx.CreateBucketIfNotExists(e.name)
+	} else {
+		bucket, err = parent.CreateBucketIfNotExists(e.name)
+	}
+	if err != nil {
+		return err
+	}
+	return writeChildren(bucket, e.children)
+}
+
+func writeChildren(bucket *bbolt.Bucket, children []*entry) error {
+	for _, child := range children {
+		if !child.bucket {
+			if err := bucket.Put(child.name, child.value); err != nil {
+				return err
+			}
+			continue
+		}
+		nested, err := bucket.CreateBucketIfNotExists(child.name)
+		if err != nil {
+			return err
+		}
+		if err := writeChildren(nested, child.children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUndoRedo(t *testing.T) {
+	openTestDatabase(t)
+	bucket := dbNode{path: []string{"a"}, kind: "bucket", name: []byte("a")}
+	key := dbNode{path: []string{"a", "k"}, kind: "key", name: []byte("k"), value: []byte("v")}
+	steps := []struct {
+		name  string
+		paths [][]string
+		fn    func() error
+		want  []string
+	}{
+		{"add bucket", [][]string{{"a"}}, func() error { return addBucket(nil, "a") }, []string{"a/"}},
+		{"add key", [][]string{{"a", "k"}}, func() error { return addKey([]string{"a"}, "k", "v") }, []string{"a/", "a/k=v"}},
+		{"edit", [][]string{{"a", "k"}}, func() error { return editNode(key, []byte("w")) }, []string{"a/", "a/k=w"}},
+		{
+			"copy", [][]string{{"b", "c"}}, func() error { return copyItem(bucket, []string{"b", "c"}) },
+			[]string{"a/", "a/k=w", "b/", "b/c/", "b/c/k=w"},
+		},
+		{
+			"move", [][]string{{"a"}, {"d"}}, func() error { return moveItem(bucket, []string{"d"}) },
+			[]string{"b/", "b/c/", "b/c/k=w", "d/", "d/k=w"},
+		},
+		{
+			"delete", [][]string{{"b"}}, func() error { return deleteEntry(dbNode{path: []string{"b"}, kind: "bucket", name: []byte("b")}) },
+			[]string{"d/", "d/k=w"},
+		},
+	}
+	states := [][]string{listPaths(t)}
+	for _, step := range steps {
+		if err := record(step.name, step.paths, step.fn); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := listPaths(t); !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("after %s: database = %v, want %v", step.name, got, step.want)
+		}
+		states = append(states, step.want)
+	}
+	for i := len(steps) - 1; i >= 0; i-- {
+		op, err := undo()
+		if err != nil {
+			t.Fatalf("undo %s: %v", steps[i].name, err)
+		}
+		if op.name != steps[i].name {
+			t.Errorf("undo returned %s, want %s", op.name, steps[i].name)
+		}
+		if got := listPaths(t); !reflect.DeepEqual(got, states[i]) {
+			t.Fatalf("after undo %s: database = %v, want %v", steps[i].name, got, states[i])
+		}
+	}
+	if _, err := undo(); err == nil {
+		t.Error("undo with empty journal succeeded")
+	}
+	for i := range steps {
+		if _, err := redo(); err != nil {
+			t.Fatalf("redo %s: %v", steps[i].name, err)
+		}
+		if got := listPaths(t); !reflect.DeepEqual(got, states[i+1]) {
+			t.Fatalf("after redo %s: database = %v, want %v", steps[i].name, got, states[i+1])
+		}
+	}
+	if _, err := redo(); err == nil {
+		t.Error("redo with empty journal succeeded")
+	}
+}
+
+func TestUndoAfterOtherChange(t *testing.T) {
+	openTestDatabase(t)
+	if err := record("add key", [][]string{{"a", "k"}}, func() error { return addKey([]string{"a"}, "k", "v") }); err != nil {
+		t.Fatal(err)
+	}
+	// not recorded, so the journal no longer matches the database
+	key := dbNode{path: []string{"a", "k"}, kind: "key", name: []byte("k"), value: []byte("v")}
+	if err := editNode(key, []byte("w")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := undo(); err == nil {
+		t.Fatal("undo of a changed key succeeded")
+	}
+	want := []string{"a/", "a/k=w"}
+	if got := listPaths(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("database = %v, want %v", got, want)
+	}
+	if len(undoJournal) != 1 {
+		t.Errorf("undo journal has %d operations, want 1", len(undoJournal))
+	}
+}
+
+func TestRecordFailure(t *testing.T) {
+	openTestDatabase(t)
+	failed := errors.New("failed")
+	if err := record("fail", [][]string{{"a"}}, func() error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("record() error = %v, want %v", err, failed)
+	}
+	if len(undoJournal) != 0 {
+		t.Errorf("failed operation was recorded")
+	}
+	if err := record("bad path", [][]string{{}}, func() error { return nil }); err == nil {
+		t.Error("record() with empty path succeeded")
+	}
+}
+
+func TestAffectedPaths(t *testing.T) {
+	openTestDatabase(t)
+	if err := addBucket(nil, "a"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		paths [][]string
+		want  [][]string
+	}{
+		{[][]string{{"a", "k"}}, [][]string{{"a", "k"}}},
+		{[][]string{{"a", "b", "c", "k"}}, [][]string{{"a", "b"}}},
+		{[][]string{{"x", "y"}}, [][]string{{"x"}}},
+		{[][]string{{"a", "k"}, {"a"}}, [][]string{{"a"}}},
+		{[][]string{{"a", "k"}, {"a", "k"}}, [][]string{{"a", "k"}}},
+	}
+	for _, test := range tests {
+		if got := affectedPaths(test.paths); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("affectedPaths(%v) = %v, want %v", test.paths, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// storageMode selects how edited text is converted to the bytes stored in the database.
+type storageMode int
+
+const (
+	storeRaw storageMode = iota
+	storeCompactJSON
+	storePrettyJSON
+)
+
+var storageModes = []string{"raw text", "compact JSON", "pretty JSON"}
+
+func (m storageMode) String() string {
+	return storageModes[m]
+}
+
+// encodeValue converts text to the stored value. Raw text is stored byte for byte; the
+// JSON modes only change whitespace, so key order, numbers and escapes are kept.
+func encodeValue(text string, mode storageMode) ([]byte, error) {
+	var data bytes.Buffer
+	switch mode {
+	case storeCompactJSON:
+		if err := json.Compact(&data, []byte(text)); err != nil {
+			return nil, errors.New("invalid JSON: " + err.Error())
+		}
+	case storePrettyJSON:
+		if err := json.Indent(&data, []byte(text), "", "\t"); err != nil {
+			return nil, errors.New("invalid JSON: " + err.Error())
+		}
+	default:
+		return []byte(text), nil
+	}
+	return data.Bytes(), nil
+}
+
+// detectStorageMode is the mode that reproduces the current value when it is stored unchanged.
+func detectStorageMode(value []byte) storageMode {
+	for _, mode := range []storageMode{storeCompactJSON, storePrettyJSON} {
+		if encoded, err := encodeValue(string(value), mode); err == nil && bytes.Equal(encoded, value) {
+			return mode
+		}
+	}
+	return storeRaw
+}
+
+// editText is the text shown for editing a value. JSON is always indented; raw values
+// are shown as they are.
+func editText(value []byte, mode storageMode) string {
+	if mode == storeRaw {
+		return string(value)
+	}
+	return prettyString(value)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEncodeValue(t *testing.T) {
+	tests := []struct {
+		text    string
+		mode    storageMode
+		want    string
+		wantErr bool
+	}{
+		{"{ \"a\" : 1 }", storeRaw, "{ \"a\" : 1 }", false},
+		{"not json", storeRaw, "not json", false},
+		{"", storeRaw, "", false},
+		{"{ \"b\": 1.50, \"a\": [1, 2] }", storeCompactJSON, `{"b":1.50,"a":[1,2]}`, false},
+		{`{"a":"é<"}`, storeCompactJSON, `{"a":"é<"}`, false},
+		{`{"a":[1,2]}`, storePrettyJSON, "{\n\t\"a\": [\n\t\t1,\n\t\t2\n\t]\n}", false},
+		{`"text"`, storePrettyJSON, `"text"`, false},
+		{"not json", storeCompactJSON, "", true},
+		{"{", storePrettyJSON, "", true},
+	}
+	for _, test := range tests {
+		got, err := encodeValue(test.text, test.mode)
+		if (err != nil) != test.wantErr {
+			t.Errorf("encodeValue(%q, %s) error = %v, want error %v", test.text, test.mode, err, test.wantErr)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("encodeValue(%q, %s) = %q, want %q", test.text, test.mode, got, test.want)
+		}
+	}
+}
+
+func TestDetectStorageMode(t *testing.T) {
+	tests := []struct {
+		value string
+		want  storageMode
+	}{
+		{`{"a":1}`, storeCompactJSON},
+		{"{\n\t\"a\": 1\n}", storePrettyJSON},
+		{"{\n  \"a\": 1\n}", storeRaw},
+		{`{"a": 1}`, storeRaw},
+		{"plain text", storeRaw},
+		{"\x00\xff", storeRaw},
+		{"42", storeCompactJSON},
+	}
+	for _, test := range tests {
+		mode := detectStorageMode([]byte(test.value))
+		if mode != test.want {
+			t.Errorf("detectStorageMode(%q) = %s, want %s", test.value, mode, test.want)
+		}
+		// storing the edit text unchanged keeps the value
+		stored, err := encodeValue(editText([]byte(test.value), mode), mode)
+		if err != nil || string(stored) != test.value {
+			t.Errorf("storing %q unchanged gives %q, %v", test.value, stored, err)
+		}
+	}
+}